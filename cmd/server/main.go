@@ -6,6 +6,7 @@ import (
 	"github.com/cloudedugcp/responseEngine/internal/actioner"
 	"github.com/cloudedugcp/responseEngine/internal/config"
 	"github.com/cloudedugcp/responseEngine/internal/db"
+	"github.com/cloudedugcp/responseEngine/internal/decoder"
 	"github.com/cloudedugcp/responseEngine/internal/server"
 )
 
@@ -45,10 +46,38 @@ func main() {
 				continue
 			}
 			actioners[name] = sg
+		case "cloudevents":
+			ce, err := actioner.NewCloudEventsActioner(acfg)
+			if err != nil {
+				log.Printf("Failed to initialize cloudevents actioner: %v", err)
+				continue
+			}
+			actioners[name] = ce
+		}
+	}
+
+	sources := make([]string, 0, len(cfg.Server.Aliases)+len(cfg.Server.Sources))
+	for _, source := range cfg.Server.Aliases {
+		sources = append(sources, source)
+	}
+	for source := range cfg.Server.Sources {
+		sources = append(sources, source)
+	}
+
+	decoders := make(map[string]decoder.Decoder)
+	for _, source := range sources {
+		if _, ok := decoders[source]; ok {
+			continue
+		}
+		dec, err := decoder.New(cfg.Server.Sources[source].Decoder)
+		if err != nil {
+			log.Printf("Failed to initialize decoder for source %s: %v", source, err)
+			continue
 		}
+		decoders[source] = dec
 	}
 
-	srv := server.NewServer(cfg, database, actioners)
+	srv := server.NewServer(cfg, database, actioners, decoders)
 	log.Printf("Starting server on port %s", cfg.Server.ListenPort)
 	if err := srv.Start(); err != nil {
 		log.Fatalf("Server failed: %v", err)
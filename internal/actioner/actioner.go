@@ -1,18 +1,87 @@
 package actioner
 
-// Event - подія від Falco
+import (
+	"errors"
+	"time"
+)
+
+// Event - подія від сенсора (Falco тощо)
 type Event struct {
-	IP       string `json:"ip"`
-	RuleName string `json:"rule"`
-	Log      string `json:"log,omitempty"` // Додаємо поле для логів, опціональне
+	IP       string            `json:"ip"`
+	RuleName string            `json:"rule"`
+	Log      string            `json:"log,omitempty"`    // Додаємо поле для логів, опціональне
+	Source   string            `json:"source,omitempty"` // Джерело події (значення аліасу, напр. falco_events)
+	Priority string            `json:"priority,omitempty"`
+	Time     time.Time         `json:"time"`
+	Hostname string            `json:"hostname,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	Fields   map[string]string `json:"output_fields,omitempty"` // Додаткові поля події (output_fields у Falco)
+}
+
+// Field - повертає значення додаткового поля події або порожній рядок
+func (e Event) Field(name string) string {
+	if e.Fields == nil {
+		return ""
+	}
+	return e.Fields[name]
 }
 
+// Value - повертає значення поля події за ім'ям: ip, rule, source, priority, hostname, log
+// або ключ додаткових полів (output_fields)
+func (e Event) Value(name string) string {
+	switch name {
+	case "ip":
+		return e.IP
+	case "rule":
+		return e.RuleName
+	case "source":
+		return e.Source
+	case "priority":
+		return e.Priority
+	case "hostname":
+		return e.Hostname
+	case "log":
+		return e.Log
+	}
+	return e.Field(name)
+}
+
+// Поля, якими сервер доповнює подію перед виконанням діячів сценарію
+const (
+	FieldScenario           = "scenario.name"       // Ім'я сценарію, що спрацював
+	FieldCorrelationKey     = "correlation.key"     // Ключ кореляції сценарію (ip, pod, host...)
+	FieldCorrelationValue   = "correlation.value"   // Значення ключа в події
+	FieldCorrelationSubject = "correlation.subject" // Суб'єкт дії: IP або "<ключ>:<значення>"
+)
+
+// Subject - суб'єкт дії сценарію; IP, якщо подію передано не зі сценарію
+func (e Event) Subject() string {
+	if s := e.Field(FieldCorrelationSubject); s != "" {
+		return s
+	}
+	return e.IP
+}
+
+// ErrNoAction - діяч виконався без помилки, але нічого не змінив (наприклад, IP уже заблоковано);
+// така дія не записується в журнал і не надсилається слухачам
+var ErrNoAction = errors.New("no action taken")
+
 // Actioner - інтерфейс для виконавців дій
 type Actioner interface {
 	Execute(event Event, params map[string]interface{}) error
 	Name() string
 }
 
+// ActionListener - діяч, який отримує повідомлення про виконані дії (block, unblock, store)
+type ActionListener interface {
+	OnAction(action, status string, event Event)
+}
+
+// ActionNotifier - діяч, що виконує відкладені дії (наприклад, розблокування) і повідомляє про них
+type ActionNotifier interface {
+	SetNotify(notify func(action, status string, event Event))
+}
+
 // ActionerConfig - конфігурація діяча
 type ActionerConfig struct {
 	Type   string                 `mapstructure:"type"`
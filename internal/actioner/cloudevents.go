@@ -0,0 +1,265 @@
+package actioner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CloudEventsActioner - діяч, що надсилає CloudEvents 1.0 про дії рушія до HTTP-приймача
+type CloudEventsActioner struct {
+	sinkURL    string
+	source     string
+	typePrefix string
+	binary     bool
+	actions    map[string]bool
+	client     *http.Client
+	outbox     chan cloudEventJob // Надсилання не блокує обробку подій
+	retries    int                // Повтори після тимчасової помилки приймача
+	retryDelay time.Duration      // Затримка першого повтору; далі подвоюється
+}
+
+const (
+	defaultCloudEventsBuffer     = 1000 // Скільки CloudEvents може чекати на надсилання
+	defaultCloudEventsRetries    = 3
+	defaultCloudEventsRetryDelay = time.Second
+)
+
+// cloudEventJob - CloudEvent, що очікує на надсилання
+type cloudEventJob struct {
+	data  cloudEventData
+	event Event
+}
+
+// cloudEventData - дані CloudEvent про дію
+type cloudEventData struct {
+	Action   string            `json:"action"`
+	Status   string            `json:"status"`
+	Scenario string            `json:"scenario,omitempty"`
+	Subject  string            `json:"subject,omitempty"`
+	IP       string            `json:"ip,omitempty"`
+	Rule     string            `json:"rule"`
+	Source   string            `json:"source,omitempty"`
+	Priority string            `json:"priority,omitempty"`
+	Hostname string            `json:"hostname,omitempty"`
+	Params   map[string]string `json:"params,omitempty"`
+}
+
+// NewCloudEventsActioner - створює новий CloudEventsActioner
+func NewCloudEventsActioner(cfg ActionerConfig) (*CloudEventsActioner, error) {
+	sinkURL, ok := cfg.Params["sink_url"].(string)
+	if !ok || sinkURL == "" {
+		return nil, fmt.Errorf("sink_url must be set for cloudevents actioner")
+	}
+	ca := &CloudEventsActioner{
+		sinkURL:    sinkURL,
+		source:     "/responseEngine",
+		typePrefix: "com.github.cloudedugcp.responseengine",
+		client:     &http.Client{Timeout: 10 * time.Second},
+		retries:    defaultCloudEventsRetries,
+		retryDelay: defaultCloudEventsRetryDelay,
+	}
+	if source, ok := cfg.Params["source"].(string); ok && source != "" {
+		ca.source = source
+	}
+	if prefix, ok := cfg.Params["type_prefix"].(string); ok && prefix != "" {
+		ca.typePrefix = prefix
+	}
+	if mode, ok := cfg.Params["mode"].(string); ok {
+		switch mode {
+		case "binary":
+			ca.binary = true
+		case "structured", "":
+		default:
+			return nil, fmt.Errorf("mode must be structured or binary, got %q", mode)
+		}
+	}
+	if timeout, ok := cfg.Params["timeout"].(int); ok {
+		ca.client.Timeout = time.Duration(timeout) * time.Second
+	}
+	if retries, ok := cfg.Params["retries"].(int); ok && retries >= 0 {
+		ca.retries = retries
+	}
+	if actions, ok := cfg.Params["actions"].([]interface{}); ok {
+		ca.actions = make(map[string]bool, len(actions))
+		for _, a := range actions {
+			if name, ok := a.(string); ok {
+				ca.actions[name] = true
+			}
+		}
+	}
+	buffer := defaultCloudEventsBuffer
+	if b, ok := cfg.Params["buffer"].(int); ok && b > 0 {
+		buffer = b
+	}
+	ca.outbox = make(chan cloudEventJob, buffer)
+	go ca.run()
+	return ca, nil
+}
+
+// Execute - ставить у чергу CloudEvent про спрацювання сценарію
+func (ca *CloudEventsActioner) Execute(event Event, params map[string]interface{}) error {
+	if !ca.emits("trigger") {
+		return nil
+	}
+	data := ca.data("trigger", "triggered", event)
+	if len(params) > 0 {
+		data.Params = make(map[string]string, len(params))
+		for k, v := range params {
+			data.Params[k] = fmt.Sprint(v)
+		}
+	}
+	if !ca.enqueue(data, event) {
+		return fmt.Errorf("CloudEvents buffer is full, dropping trigger event for %s", event.Subject())
+	}
+	return nil
+}
+
+// OnAction - ставить у чергу CloudEvent про дію іншого діяча
+func (ca *CloudEventsActioner) OnAction(action, status string, event Event) {
+	if !ca.emits(action) {
+		return
+	}
+	if !ca.enqueue(ca.data(action, status, event), event) {
+		log.Printf("CloudEvents buffer is full, dropping %s event for %s", action, event.Subject())
+	}
+}
+
+// emits - чи надсилати CloudEvents про дію (параметр actions; якщо не задано - про всі)
+func (ca *CloudEventsActioner) emits(action string) bool {
+	return ca.actions == nil || ca.actions[action]
+}
+
+// enqueue - додає CloudEvent до черги надсилання; false, якщо черга заповнена
+func (ca *CloudEventsActioner) enqueue(data cloudEventData, event Event) bool {
+	select {
+	case ca.outbox <- cloudEventJob{data: data, event: event}:
+		return true
+	default:
+		return false
+	}
+}
+
+// run - надсилає CloudEvents з черги по одному; після тимчасової помилки (мережа, 5xx, 429)
+// повторює з наростаючою затримкою, доки не вичерпано retries. Наступні CloudEvents чекають,
+// тож приймач отримує їх у порядку дій
+func (ca *CloudEventsActioner) run() {
+	for job := range ca.outbox {
+		delay := ca.retryDelay
+		for attempt := 0; ; attempt++ {
+			err := ca.send(job.data, job.event)
+			if err == nil {
+				break
+			}
+			if attempt >= ca.retries || !retriable(err) {
+				log.Printf("Failed to emit CloudEvent for %s of %s: %v", job.data.Action, job.event.Subject(), err)
+				break
+			}
+			log.Printf("Failed to emit CloudEvent for %s of %s, retrying in %s: %v", job.data.Action, job.event.Subject(), delay, err)
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+}
+
+// sinkStatusError - приймач відповів статусом помилки
+type sinkStatusError struct {
+	code   int
+	status string
+}
+
+func (e *sinkStatusError) Error() string {
+	return "CloudEvent sink returned " + e.status
+}
+
+// retriable - чи має сенс повторити надсилання: відмова приймача (4xx, крім 429) не минає з часом
+func retriable(err error) bool {
+	var se *sinkStatusError
+	if errors.As(err, &se) {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// Name - повертає ім'я діяча
+func (ca *CloudEventsActioner) Name() string { return "cloudevents" }
+
+// data - формує дані CloudEvent
+func (ca *CloudEventsActioner) data(action, status string, event Event) cloudEventData {
+	return cloudEventData{
+		Action:   action,
+		Status:   status,
+		Scenario: event.Field(FieldScenario),
+		Subject:  event.Subject(),
+		IP:       event.IP,
+		Rule:     event.RuleName,
+		Source:   event.Source,
+		Priority: event.Priority,
+		Hostname: event.Hostname,
+	}
+}
+
+// send - надсилає CloudEvent у structured або binary режимі HTTP
+func (ca *CloudEventsActioner) send(data cloudEventData, event Event) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CloudEvent data: %v", err)
+	}
+
+	id := uuid.NewString()
+	ceType := ca.typePrefix + "." + data.Action
+	ceTime := time.Now().UTC().Format(time.RFC3339Nano)
+
+	var req *http.Request
+	if ca.binary {
+		req, err = http.NewRequest(http.MethodPost, ca.sinkURL, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Ce-Specversion", "1.0")
+		req.Header.Set("Ce-Id", id)
+		req.Header.Set("Ce-Source", ca.source)
+		req.Header.Set("Ce-Type", ceType)
+		req.Header.Set("Ce-Time", ceTime)
+		if subject := event.Subject(); subject != "" {
+			req.Header.Set("Ce-Subject", subject)
+		}
+	} else {
+		body, err := json.Marshal(map[string]interface{}{
+			"specversion":     "1.0",
+			"id":              id,
+			"source":          ca.source,
+			"type":            ceType,
+			"time":            ceTime,
+			"subject":         event.Subject(),
+			"datacontenttype": "application/json",
+			"data":            json.RawMessage(payload),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal CloudEvent: %v", err)
+		}
+		req, err = http.NewRequest(http.MethodPost, ca.sinkURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/cloudevents+json")
+	}
+
+	resp, err := ca.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send CloudEvent: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &sinkStatusError{code: resp.StatusCode, status: resp.Status}
+	}
+	log.Printf("Emitted CloudEvent %s (%s) for %s", ceType, id, event.Subject())
+	return nil
+}
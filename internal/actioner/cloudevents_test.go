@@ -0,0 +1,176 @@
+package actioner
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sink - приймач CloudEvents, що відповідає статусами зі списку по черзі (далі - 200)
+type sink struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+	received chan struct{}
+}
+
+func newSink(statuses ...int) (*sink, *httptest.Server) {
+	s := &sink{statuses: statuses, received: make(chan struct{}, 100)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		json.NewDecoder(r.Body).Decode(&body)
+		s.mu.Lock()
+		status := http.StatusOK
+		if len(s.requests) < len(s.statuses) {
+			status = s.statuses[len(s.requests)]
+		}
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		s.received <- struct{}{}
+	}))
+	return s, srv
+}
+
+// wait - чекає на n запитів і переконується, що зайвих не надійшло
+func (s *sink) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.received:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("sink got %d requests, want %d", i, n)
+		}
+	}
+	select {
+	case <-s.received:
+		t.Fatalf("sink got more than %d requests", n)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func newTestCloudEventsActioner(t *testing.T, url string, params map[string]interface{}) *CloudEventsActioner {
+	t.Helper()
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	params["sink_url"] = url
+	ca, err := NewCloudEventsActioner(ActionerConfig{Params: params})
+	if err != nil {
+		t.Fatalf("NewCloudEventsActioner: %v", err)
+	}
+	ca.retryDelay = time.Millisecond
+	return ca
+}
+
+func TestCloudEventsOutboxRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		retries  int
+		statuses []int
+		want     int // Скільки разів надсилалася перша подія
+	}{
+		{name: "delivered first time", retries: 3, want: 1},
+		{name: "server error retried", retries: 3, statuses: []int{503, 500}, want: 3},
+		{name: "rate limited retried", retries: 3, statuses: []int{429}, want: 2},
+		{name: "retries exhausted", retries: 2, statuses: []int{503, 503, 503}, want: 3},
+		{name: "client error not retried", retries: 3, statuses: []int{400}, want: 1},
+		{name: "retries disabled", retries: 0, statuses: []int{503}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, srv := newSink(tt.statuses...)
+			defer srv.Close()
+			ca := newTestCloudEventsActioner(t, srv.URL, map[string]interface{}{"retries": tt.retries})
+
+			if err := ca.Execute(Event{IP: "203.0.113.7", RuleName: "r"}, nil); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			s.wait(t, tt.want)
+
+			// Наступна подія надсилається після першої
+			ca.OnAction("block", "success", Event{IP: "203.0.113.8", RuleName: "r"})
+			s.wait(t, 1)
+			var data cloudEventData
+			if err := json.Unmarshal(s.bodies[len(s.bodies)-1], &struct {
+				Data *cloudEventData `json:"data"`
+			}{&data}); err != nil || data.IP != "203.0.113.8" || data.Action != "block" {
+				t.Errorf("last CloudEvent data = %+v, %v; want block of 203.0.113.8", data, err)
+			}
+		})
+	}
+}
+
+func TestCloudEventsModes(t *testing.T) {
+	event := Event{IP: "203.0.113.7", RuleName: "Suspicious", Fields: map[string]string{FieldScenario: "block_ip"}}
+	t.Run("structured", func(t *testing.T) {
+		s, srv := newSink()
+		defer srv.Close()
+		ca := newTestCloudEventsActioner(t, srv.URL, nil)
+		ca.OnAction("block", "success", event)
+		s.wait(t, 1)
+
+		if ct := s.requests[0].Header.Get("Content-Type"); ct != "application/cloudevents+json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		var ce struct {
+			SpecVersion string         `json:"specversion"`
+			Type        string         `json:"type"`
+			Source      string         `json:"source"`
+			Subject     string         `json:"subject"`
+			Data        cloudEventData `json:"data"`
+		}
+		if err := json.Unmarshal(s.bodies[0], &ce); err != nil {
+			t.Fatalf("invalid CloudEvent: %v", err)
+		}
+		if ce.SpecVersion != "1.0" || ce.Type != "com.github.cloudedugcp.responseengine.block" || ce.Source != "/responseEngine" || ce.Subject != "203.0.113.7" {
+			t.Errorf("CloudEvent attributes = %+v", ce)
+		}
+		if ce.Data.Scenario != "block_ip" || ce.Data.Status != "success" {
+			t.Errorf("CloudEvent data = %+v", ce.Data)
+		}
+	})
+	t.Run("binary", func(t *testing.T) {
+		s, srv := newSink()
+		defer srv.Close()
+		ca := newTestCloudEventsActioner(t, srv.URL, map[string]interface{}{"mode": "binary", "type_prefix": "example", "actions": []interface{}{"unblock"}})
+		ca.OnAction("block", "success", event) // Не входить до actions
+		ca.OnAction("unblock", "success", event)
+		s.wait(t, 1)
+
+		h := s.requests[0].Header
+		if h.Get("Ce-Specversion") != "1.0" || h.Get("Ce-Type") != "example.unblock" || h.Get("Ce-Subject") != "203.0.113.7" || h.Get("Ce-Id") == "" {
+			t.Errorf("CloudEvent headers = %v", h)
+		}
+		var data cloudEventData
+		if err := json.Unmarshal(s.bodies[0], &data); err != nil || data.Action != "unblock" {
+			t.Errorf("CloudEvent data = %+v, %v", data, err)
+		}
+	})
+}
+
+func TestCloudEventsBufferFull(t *testing.T) {
+	ca := &CloudEventsActioner{outbox: make(chan cloudEventJob, 1)} // Без run - черга не спорожняється
+	if err := ca.Execute(Event{IP: "203.0.113.7"}, nil); err != nil {
+		t.Fatalf("first Execute: %v", err)
+	}
+	if err := ca.Execute(Event{IP: "203.0.113.7"}, nil); err == nil {
+		t.Errorf("Execute succeeded with a full buffer")
+	}
+}
+
+func TestNewCloudEventsActionerErrors(t *testing.T) {
+	for name, params := range map[string]map[string]interface{}{
+		"no sink_url":  {},
+		"unknown mode": {"sink_url": "http://sink", "mode": "batched"},
+	} {
+		if _, err := NewCloudEventsActioner(ActionerConfig{Params: params}); err == nil {
+			t.Errorf("%s: NewCloudEventsActioner accepted %v", name, params)
+		}
+	}
+}
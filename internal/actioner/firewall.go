@@ -21,6 +21,7 @@ type FirewallActioner struct {
 	client          *compute.FirewallsClient
 	db              *db.Database
 	multiplyTimeout bool
+	notify          func(action, status string, event Event)
 }
 
 // NewFirewallActioner - створює новий FirewallActioner
@@ -53,9 +54,14 @@ func NewFirewallActioner(cfg ActionerConfig, database *db.Database) (*FirewallAc
 
 // Execute - виконує блокування IP
 func (fa *FirewallActioner) Execute(event Event, params map[string]interface{}) error {
+	if event.IP == "" {
+		// Сценарій з ключем, відмінним від ip, спрацював на подію без IP - повтор не допоможе
+		log.Printf("Warning: event for %s has no IP to block (Rule=%s), check the scenario key and actioners", event.Subject(), event.RuleName)
+		return ErrNoAction
+	}
 	if fa.isIPBlocked(event.IP) {
 		log.Printf("IP %s is already blocked, skipping further action", event.IP)
-		return nil // IP уже заблокована, виходимо без помилки
+		return ErrNoAction // IP уже заблокована - не помилка, але й не нове блокування
 	}
 
 	var priority int
@@ -81,16 +87,19 @@ func (fa *FirewallActioner) Execute(event Event, params map[string]interface{})
 		baseTimeout = fa.timeout
 	}
 
-	blockCount, err := fa.db.GetBlockCount(event.IP)
+	// Лічильник блокувань і журнал ведуться за суб'єктом сценарію (IP або "<ключ>:<значення>"),
+	// тим самим, за яким сервер записує блокування
+	subject := event.Subject()
+	blockCount, err := fa.db.GetBlockCount(subject)
 	if err != nil {
-		log.Printf("Failed to get block count for IP %s: %v", event.IP, err)
+		log.Printf("Failed to get block count for %s: %v", subject, err)
 		blockCount = 0
 	}
 
 	timeout := baseTimeout
 	if fa.multiplyTimeout {
 		timeout = baseTimeout * time.Duration(blockCount+1)
-		log.Printf("Blocking IP %s for %s (block count: %d)", event.IP, timeout, blockCount+1)
+		log.Printf("Blocking IP %s (%s) for %s (block count: %d)", event.IP, subject, timeout, blockCount+1)
 	}
 
 	if err := fa.blockIP(event.IP, priority, description); err != nil {
@@ -101,7 +110,10 @@ func (fa *FirewallActioner) Execute(event Event, params map[string]interface{})
 			log.Printf("Failed to unblock IP %s: %v", event.IP, err)
 		} else {
 			log.Printf("Successfully unblocked IP %s after %s", event.IP, timeout)
-			fa.db.LogAction(event.IP, "block", "unblocked", time.Now())
+			fa.db.LogAction(subject, "block", "unblocked", time.Now())
+			if fa.notify != nil {
+				fa.notify("unblock", "unblocked", event)
+			}
 		}
 	})
 	return nil
@@ -110,6 +122,11 @@ func (fa *FirewallActioner) Execute(event Event, params map[string]interface{})
 // Name - повертає ім'я діяча
 func (fa *FirewallActioner) Name() string { return "firewall" }
 
+// SetNotify - задає функцію повідомлення про розблокування
+func (fa *FirewallActioner) SetNotify(notify func(action, status string, event Event)) {
+	fa.notify = notify
+}
+
 // isIPBlocked - перевіряє, чи IP уже заблоковано
 func (fa *FirewallActioner) isIPBlocked(ip string) bool {
 	req := &computepb.ListFirewallsRequest{Project: fa.projectID}
@@ -56,11 +56,11 @@ func (sa *SigmaActioner) Execute(event Event, params map[string]interface{}) err
 	prefix := params["prefix"].(string)
 	ctx := context.Background()
 	bucket := sa.client.Bucket(sa.bucketName)
-	objectName := fmt.Sprintf("%s%s_%d.yaml", prefix, event.IP, time.Now().UnixNano())
+	objectName := fmt.Sprintf("%s%s_%d.yaml", prefix, objectSubject(event), time.Now().UnixNano())
 
 	// Формуємо базовий Sigma-запис
 	sigmaRule := SigmaRule{
-		Title:       fmt.Sprintf("Suspicious Activity Detected for IP %s", event.IP),
+		Title:       fmt.Sprintf("Suspicious Activity Detected for %s", event.Subject()),
 		Description: fmt.Sprintf("Detected %s: %s", event.RuleName, event.Log),
 		LogSource: struct {
 			Category string `yaml:"category"`
@@ -82,11 +82,15 @@ func (sa *SigmaActioner) Execute(event Event, params map[string]interface{}) err
 		Fields: []string{"src_ip", "event"},
 		Level:  "high",
 	}
+	if key := event.Field(FieldCorrelationKey); key != "" && key != "ip" {
+		sigmaRule.Detection.Selection[key] = event.Field(FieldCorrelationValue)
+		sigmaRule.Fields = append(sigmaRule.Fields, key)
+	}
 
 	// Перетворюємо у YAML
 	yamlData, err := yaml.Marshal(&sigmaRule)
 	if err != nil {
-		log.Printf("Failed to marshal Sigma rule for IP %s: %v", event.IP, err)
+		log.Printf("Failed to marshal Sigma rule for %s: %v", event.Subject(), err)
 		return fmt.Errorf("failed to marshal Sigma rule: %v", err)
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
@@ -51,11 +52,11 @@ func (sa *StorageActioner) Execute(event Event, params map[string]interface{}) e
 	prefix := params["prefix"].(string)
 	ctx := context.Background()
 	bucket := sa.client.Bucket(sa.bucketName)
-	objectName := fmt.Sprintf("%s%s_%d", prefix, event.IP, time.Now().UnixNano())
+	objectName := fmt.Sprintf("%s%s_%d", prefix, objectSubject(event), time.Now().UnixNano())
 
 	logData := event.Log
 	if logData == "" {
-		logData = fmt.Sprintf("IP: %s, Rule: %s, Time: %s", event.IP, event.RuleName, time.Now().Format(time.RFC3339))
+		logData = fmt.Sprintf("Subject: %s, IP: %s, Rule: %s, Time: %s", event.Subject(), event.IP, event.RuleName, time.Now().Format(time.RFC3339))
 		log.Printf("Warning: No log provided for event IP=%s, Rule=%s, using default data", event.IP, event.RuleName)
 	}
 
@@ -81,3 +82,8 @@ func (sa *StorageActioner) Execute(event Event, params map[string]interface{}) e
 
 // Name - повертає ім'я діяча
 func (sa *StorageActioner) Name() string { return "storage" }
+
+// objectSubject - суб'єкт події для імені об'єкта; "/" (як у namespace/pod) не створює підкаталогів
+func objectSubject(event Event) string {
+	return strings.ReplaceAll(event.Subject(), "/", "_")
+}
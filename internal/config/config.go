@@ -1,7 +1,10 @@
 package config
 
 import (
+	"time"
+
 	"github.com/cloudedugcp/responseEngine/internal/actioner" // Імпорт для ActionerConfig
+	"github.com/cloudedugcp/responseEngine/internal/decoder"
 	"github.com/cloudedugcp/responseEngine/internal/scenario"
 	"github.com/spf13/viper"
 )
@@ -14,14 +17,145 @@ type Config struct {
 }
 
 type ServerConfig struct {
-	ListenPort string            `mapstructure:"port"`
-	Aliases    map[string]string `mapstructure:"aliases"`
+	ListenPort  string                  `mapstructure:"port"`
+	MaxBodySize int64                   `mapstructure:"max_body_size"` // Максимальний розмір тіла запиту в байтах (за замовчуванням 10 MiB)
+	Aliases     map[string]string       `mapstructure:"aliases"`       // Шлях -> ім'я джерела
+	Sources     map[string]SourceConfig `mapstructure:"sources"`       // Ім'я джерела -> налаштування
+	Syslog      []SyslogConfig          `mapstructure:"syslog"`
+	FalcoGRPC   []FalcoGRPCConfig       `mapstructure:"falco_grpc"`
+	CloudEvents CloudEventsConfig       `mapstructure:"cloudevents"`
+	TLS         TLSConfig               `mapstructure:"tls"`
+	Dashboard   DashboardConfig         `mapstructure:"dashboard"`
+	Queue       QueueConfig             `mapstructure:"queue"`
+	History     HistoryConfig           `mapstructure:"history"`
+}
+
+// HistoryConfig - журнал отриманих подій, за яким рахуються умови сценаріїв
+type HistoryConfig struct {
+	Retention time.Duration `mapstructure:"retention"` // Скільки зберігати події (за замовчуванням 168h); має перевищувати найдовше time_window
+}
+
+// QueueConfig - асинхронна обробка подій пулом обробників
+type QueueConfig struct {
+	Workers     int           `mapstructure:"workers"`      // 0 - синхронна обробка в запиті (за замовчуванням)
+	Size        int           `mapstructure:"size"`         // Місткість черги (за замовчуванням 1000)
+	RetryAfter  time.Duration `mapstructure:"retry_after"`  // Значення Retry-After при переповненні (за замовчуванням 5s)
+	FullStatus  int           `mapstructure:"full_status"`  // Статус при переповненні: 429 (за замовчуванням) або 503
+	Durable     bool          `mapstructure:"durable"`      // Зберігати прийняті події в БД до завершення діячів і повторювати після перезапуску
+	MaxAttempts int           `mapstructure:"max_attempts"` // Скільки спроб дати діячам, що збоїли, перш ніж позначити подію failed (за замовчуванням 3)
+}
+
+// TLSConfig - TLS/mTLS для HTTP-сервера
+type TLSConfig struct {
+	CertFile       string        `mapstructure:"cert_file"`
+	KeyFile        string        `mapstructure:"key_file"`
+	MinVersion     string        `mapstructure:"min_version"`     // "1.2" (за замовчуванням) або "1.3"
+	ClientCAFile   string        `mapstructure:"client_ca_file"`  // CA для перевірки клієнтських сертифікатів (mTLS)
+	ClientAuth     string        `mapstructure:"client_auth"`     // require (за замовчуванням з client_ca_file), verify_if_given або request
+	ReloadInterval time.Duration `mapstructure:"reload_interval"` // Як часто перевіряти зміну файлів (за замовчуванням 30s)
+}
+
+// DashboardConfig - окремий слухач для дашборду та метрик
+type DashboardConfig struct {
+	Port string `mapstructure:"port"` // Якщо порожньо - дашборд на порту інжесту
+	TLS  bool   `mapstructure:"tls"`  // Використовувати сертифікат сервера (без вимоги клієнтського)
+}
+
+// CloudEventsConfig - маршрутизація вхідних CloudEvents до джерел за type/source
+type CloudEventsConfig struct {
+	Routes []CloudEventsRoute `mapstructure:"routes"`
+}
+
+// CloudEventsRoute - правило маршрутизації CloudEvent
+type CloudEventsRoute struct {
+	Type   string `mapstructure:"type"`   // Тип CloudEvent; "prefix*" - за префіксом, порожній - будь-який
+	Source string `mapstructure:"source"` // Атрибут source CloudEvent; "prefix*" - за префіксом
+	To     string `mapstructure:"to"`     // Джерело, чий декодер розбирає data
+}
+
+// FalcoGRPCConfig - налаштування підписки на gRPC outputs service Falco
+type FalcoGRPCConfig struct {
+	Address      string        `mapstructure:"address"` // unix:///run/falco/falco.sock або host:port
+	Source       string        `mapstructure:"source"`  // Ім'я джерела для подій (декодер falco цього джерела задає ip_fields)
+	CertFile     string        `mapstructure:"cert_file"`
+	KeyFile      string        `mapstructure:"key_file"`
+	CAFile       string        `mapstructure:"ca_file"`
+	ServerName   string        `mapstructure:"server_name"`
+	Insecure     bool          `mapstructure:"insecure"` // Дозволити TCP без TLS (за замовчуванням TCP потребує mTLS)
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+// SyslogConfig - налаштування слухача syslog
+type SyslogConfig struct {
+	Protocol string `mapstructure:"protocol"` // udp, tcp або tls
+	Address  string `mapstructure:"address"`
+	Source   string `mapstructure:"source"` // Джерело, чий декодер розбирає повідомлення
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+
+	AllowedPeers []string `mapstructure:"allowed_peers"` // CIDR або адреси відправників; порожній - приймати від усіх
+
+	IdleTimeout    time.Duration `mapstructure:"idle_timeout"`    // TCP/TLS: з'єднання без нового повідомлення закривається (0 - 5m)
+	MaxConnections int           `mapstructure:"max_connections"` // TCP/TLS: одночасних з'єднань, понад - відхиляються (0 - 100)
+}
+
+// SourceConfig - налаштування джерела подій
+type SourceConfig struct {
+	Decoder   decoder.Config  `mapstructure:"decoder"`
+	PubSub    PubSubConfig    `mapstructure:"pubsub"`
+	Auth      *AuthConfig     `mapstructure:"auth"` // nil - без автентифікації
+	Dedup     DedupConfig     `mapstructure:"dedup"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+}
+
+// RateLimitConfig - обмеження швидкості надходження подій джерела (token bucket)
+type RateLimitConfig struct {
+	Alias           RateConfig    `mapstructure:"alias"`            // Спільний ліміт подій джерела
+	Client          RateConfig    `mapstructure:"client"`           // Ліміт запитів від однієї адреси клієнта (надлишок - 429)
+	IP              RateConfig    `mapstructure:"ip"`               // Ліміт подій для одного IP з події
+	OnExcess        string        `mapstructure:"on_excess"`        // drop (за замовчуванням), queue або aggregate
+	MaxDelay        time.Duration `mapstructure:"max_delay"`        // Для queue: довше чекати не можна - подія відкидається (за замовчуванням 1m)
+	AggregateWindow time.Duration `mapstructure:"aggregate_window"` // Для aggregate: вікно підсумкової події (за замовчуванням 1m)
+}
+
+// RateConfig - параметри token bucket
+type RateConfig struct {
+	Rate  float64 `mapstructure:"rate"`  // Подій (запитів) за секунду; 0 - без обмеження
+	Burst int     `mapstructure:"burst"` // Розмір bucket (за замовчуванням - rate, але не менше 1)
+}
+
+// DedupConfig - придушення однакових подій у межах вікна
+type DedupConfig struct {
+	Window time.Duration `mapstructure:"window"` // 0 - вимкнено
+	Fields []string      `mapstructure:"fields"` // ip, rule, source, priority, hostname, log або ключі output_fields (за замовчуванням ip і rule)
+}
+
+// AuthConfig - автентифікація запитів до аліасів джерела (усі задані методи мають пройти)
+type AuthConfig struct {
+	Token            string        `mapstructure:"token"`              // Bearer-токен; підтримує ${ENV}
+	HMACSecret       string        `mapstructure:"hmac_secret"`        // Ключ HMAC-SHA256 для "<timestamp>.<body>"; підтримує ${ENV}
+	SignatureHeader  string        `mapstructure:"signature_header"`   // За замовчуванням X-Signature (hex, можна з префіксом sha256=)
+	TimestampHeader  string        `mapstructure:"timestamp_header"`   // За замовчуванням X-Timestamp (Unix-секунди)
+	ReplayWindow     time.Duration `mapstructure:"replay_window"`      // За замовчуванням 5m
+	ClientCertSHA256 []string      `mapstructure:"client_cert_sha256"` // Дозволені відбитки клієнтських сертифікатів (mTLS)
+}
+
+// PubSubConfig - налаштування прийому push-повідомлень Google Cloud Pub/Sub
+type PubSubConfig struct {
+	Enabled          bool   `mapstructure:"enabled"`           // Тіло запиту - push-конверт Pub/Sub; обробляється синхронно, без черги
+	DecoderAttribute string `mapstructure:"decoder_attribute"` // Атрибут з іменем джерела, чий декодер розбирає message.data
 }
 
 type Scenario struct {
 	Name       string                       `mapstructure:"name"`
 	FalcoRule  string                       `mapstructure:"falco_rule"`
+	Match      scenario.RuleMatch           `mapstructure:",squash"`  // rules, rule_regex, tags, min_priority
+	Sequence   *scenario.SequenceConfig     `mapstructure:"sequence"` // Замість правила - ланцюжок правил по порядку
+	Source     string                       `mapstructure:"source"`   // Якщо задано, сценарій реагує лише на події з цього джерела
+	Key        string                       `mapstructure:"key"`      // Ключ кореляції: ip (за замовчуванням), container, pod, namespace, host або user
 	Conditions *scenario.ScenarioConditions `mapstructure:"conditions"`
+	When       string                       `mapstructure:"when"`  // CEL-вираз над подією та журналом подій
+	Dedup      DedupConfig                  `mapstructure:"dedup"` // Повтори в межах вікна не запускають сценарій; ключ - значення key сценарію плюс fields (за замовчуванням rule)
 	Actioners  []ScenarioActioner           `mapstructure:"actioners"`
 }
 
@@ -48,10 +48,23 @@ func NewDatabase(path string) (*Database, error) {
 		return nil, err
 	}
 
-	return &Database{conn: conn}, nil
+	// SQLite допускає лише одного записувача - серіалізуємо доступ з обробників черги
+	conn.SetMaxOpenConns(1)
+
+	d := &Database{conn: conn}
+	if err := d.createQueueTable(); err != nil {
+		return nil, err
+	}
+	if err := d.createEventsTable(); err != nil {
+		return nil, err
+	}
+	if err := d.createSequencesTable(); err != nil {
+		return nil, err
+	}
+	return d, nil
 }
 
-// LogAction - записує або оновлює дію для IP
+// LogAction - записує або оновлює дію для IP (або іншого суб'єкта, див. Subject)
 func (d *Database) LogAction(ip, event, status string, timestamp time.Time) error {
 	var exists bool
 	err := d.conn.QueryRow("SELECT EXISTS(SELECT 1 FROM ip_actions WHERE ip = ?)", ip).Scan(&exists)
@@ -61,10 +74,24 @@ func (d *Database) LogAction(ip, event, status string, timestamp time.Time) erro
 	}
 
 	if !exists {
-		_, err = d.conn.Exec(`
-            INSERT INTO ip_actions (ip, last_event, attempt_count, last_attempt_time, status)
-            VALUES (?, ?, 1, ?, ?)
-        `, ip, event, timestamp, status)
+		// Суб'єкти, відмінні від IP, не мають запису "received", тож першою може бути і сама дія
+		switch status {
+		case "blocked":
+			_, err = d.conn.Exec(`
+                INSERT INTO ip_actions (ip, last_event, attempt_count, last_attempt_time, block_time, status, block_count)
+                VALUES (?, ?, 0, ?, ?, ?, 1)
+            `, ip, event, timestamp, timestamp, status)
+		case "unblocked":
+			_, err = d.conn.Exec(`
+                INSERT INTO ip_actions (ip, last_event, attempt_count, last_attempt_time, unblock_time, status)
+                VALUES (?, ?, 0, ?, ?, ?)
+            `, ip, event, timestamp, timestamp, status)
+		default:
+			_, err = d.conn.Exec(`
+                INSERT INTO ip_actions (ip, last_event, attempt_count, last_attempt_time, status)
+                VALUES (?, ?, 1, ?, ?)
+            `, ip, event, timestamp, status)
+		}
 	} else {
 		switch status {
 		case "received":
@@ -99,25 +126,6 @@ func (d *Database) LogAction(ip, event, status string, timestamp time.Time) erro
 	return err
 }
 
-// CountEvents - повертає кількість спроб для IP за період
-func (d *Database) CountEvents(ip string, window time.Duration) (int, error) {
-	cutoff := time.Now().Add(-window)
-	var count int
-	err := d.conn.QueryRow(`
-        SELECT attempt_count 
-        FROM ip_actions 
-        WHERE ip = ? AND last_attempt_time >= ?
-    `, ip, cutoff).Scan(&count)
-	if err == sql.ErrNoRows {
-		return 0, nil
-	}
-	if err != nil {
-		log.Printf("Error counting events for IP %s: %v", ip, err)
-		return 0, err
-	}
-	return count, nil
-}
-
 // GetBlockCount - повертає кількість заблокувань для IP
 func (d *Database) GetBlockCount(ip string) (int, error) {
 	var count int
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	database, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func TestLogActionBlockCycle(t *testing.T) {
+	tests := []struct {
+		name     string
+		subject  string
+		received bool // Перед блокуванням записано отриману подію (як для IP)
+	}{
+		{name: "ip", subject: "203.0.113.7", received: true},
+		{name: "pod without received row", subject: Subject("pod", "ns/a")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDatabase(t)
+			now := time.Now()
+			if tt.received {
+				if err := d.LogAction(tt.subject, "rule", "received", now); err != nil {
+					t.Fatalf("LogAction received: %v", err)
+				}
+			}
+			for i := 1; i <= 2; i++ {
+				if err := d.LogAction(tt.subject, "block", "blocked", now); err != nil {
+					t.Fatalf("LogAction blocked: %v", err)
+				}
+				if got, _ := d.GetBlockCount(tt.subject); got != i {
+					t.Fatalf("block count after block %d = %d, want %d", i, got, i)
+				}
+				if err := d.LogAction(tt.subject, "block", "unblocked", now.Add(time.Minute)); err != nil {
+					t.Fatalf("LogAction unblocked: %v", err)
+				}
+			}
+
+			actions, err := d.GetActions()
+			if err != nil || len(actions) != 1 {
+				t.Fatalf("GetActions = %v, %v; want one row", actions, err)
+			}
+			a := actions[0]
+			if a.Status != "unblocked" || a.BlockTime.IsZero() || a.UnblockTime.IsZero() {
+				t.Errorf("row = %+v, want unblocked with block and unblock time", a)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// keyColumns - ключі кореляції та колонки журналу, в яких зберігаються їхні значення
+var keyColumns = map[string]string{
+	"ip":        "ip",
+	"container": "container_id",
+	"pod":       "pod",
+	"namespace": "namespace",
+	"host":      "host",
+	"user":      "user_name",
+}
+
+// EventRecord - запис журналу подій
+type EventRecord struct {
+	IP          string
+	Rule        string
+	Source      string
+	Timestamp   time.Time
+	PayloadHash string
+	Keys        map[string]string // Значення інших ключів кореляції: container, pod, namespace, host, user
+	QueueID     int64             // Запис durable-черги; подія з тим самим QueueID записується лише раз
+	Count       int               // Скільки подій представляє запис (підсумкова подія ліміту швидкості); 0 - одна
+}
+
+// Subject - ідентифікатор ключа кореляції в ip_actions: сам IP або "<ключ>:<значення>"
+func Subject(key, value string) string {
+	if key == "" || key == "ip" {
+		return value
+	}
+	return key + ":" + value
+}
+
+// createEventsTable - створює журнал усіх отриманих подій
+func (d *Database) createEventsTable() error {
+	_, err := d.conn.Exec(`
+        CREATE TABLE IF NOT EXISTS events (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            ip TEXT NOT NULL,
+            rule TEXT NOT NULL,
+            source TEXT NOT NULL,
+            timestamp DATETIME NOT NULL,
+            payload_hash TEXT NOT NULL
+        );
+        CREATE INDEX IF NOT EXISTS events_ip_time ON events (ip, timestamp);
+        CREATE INDEX IF NOT EXISTS events_time ON events (timestamp);
+    `)
+	if err != nil {
+		return err
+	}
+
+	// Колонки ключів кореляції додаються і до журналів, створених раніше
+	for key, column := range keyColumns {
+		if key == "ip" {
+			continue
+		}
+		_, err := d.conn.Exec(fmt.Sprintf("ALTER TABLE events ADD COLUMN %s TEXT NOT NULL DEFAULT ''", column))
+		if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return err
+		}
+		_, err = d.conn.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS events_%s_time ON events (%s, timestamp)", column, column))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Подія з durable-черги, повторена після перезапуску, не рахується в журналі вдруге
+	_, err = d.conn.Exec("ALTER TABLE events ADD COLUMN queue_id INTEGER")
+	if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+		return err
+	}
+	_, err = d.conn.Exec("CREATE UNIQUE INDEX IF NOT EXISTS events_queue_id ON events (queue_id) WHERE queue_id IS NOT NULL")
+	if err != nil {
+		return err
+	}
+
+	// Підсумкова подія ліміту швидкості рахується як усі агреговані нею події
+	_, err = d.conn.Exec("ALTER TABLE events ADD COLUMN count INTEGER NOT NULL DEFAULT 1")
+	if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+		return err
+	}
+	return nil
+}
+
+// RecordEvent - додає подію до журналу; false, якщо подію з цим QueueID уже записано.
+// Час події задає відправник, тож майбутній час обмежується поточним: інакше подія
+// потрапляла б у кожне вікно підрахунку і ніколи не видалялася б з журналу
+func (d *Database) RecordEvent(rec EventRecord) (bool, error) {
+	if now := time.Now(); rec.Timestamp.IsZero() || rec.Timestamp.After(now) {
+		rec.Timestamp = now
+	}
+	if rec.Count <= 0 {
+		rec.Count = 1
+	}
+	var queueID sql.NullInt64
+	if rec.QueueID != 0 {
+		queueID = sql.NullInt64{Int64: rec.QueueID, Valid: true}
+	}
+	res, err := d.conn.Exec(`
+        INSERT OR IGNORE INTO events (ip, rule, source, timestamp, payload_hash, container_id, pod, namespace, host, user_name, queue_id, count)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, rec.IP, rec.Rule, rec.Source, rec.Timestamp.UTC(), rec.PayloadHash,
+		rec.Keys["container"], rec.Keys["pod"], rec.Keys["namespace"], rec.Keys["host"], rec.Keys["user"], queueID, rec.Count)
+	if err != nil {
+		log.Printf("Error recording event for IP %s: %v", rec.IP, err)
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// windowStart - початок вікна підрахунку для ключа: не раніше останнього розблокування
+func (d *Database) windowStart(key, value string, window time.Duration) (time.Time, error) {
+	since := time.Now().Add(-window).UTC()
+
+	var unblock sql.NullTime
+	err := d.conn.QueryRow("SELECT unblock_time FROM ip_actions WHERE ip = ?", Subject(key, value)).Scan(&unblock)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error getting unblock time for %s %s: %v", key, value, err)
+		return since, err
+	}
+	if unblock.Valid && unblock.Time.After(since) {
+		since = unblock.Time.UTC()
+	}
+	return since, nil
+}
+
+// CountEventsByKey - повертає кількість подій зі значенням ключа кореляції за період (з урахуванням
+// лише подій після останнього розблокування); rule обмежує підрахунок одним правилом.
+// Підсумкова подія ліміту швидкості рахується як усі агреговані нею події
+func (d *Database) CountEventsByKey(key, value, rule string, window time.Duration) (int, error) {
+	column, ok := keyColumns[key]
+	if !ok {
+		return 0, fmt.Errorf("unknown correlation key %q", key)
+	}
+	since, err := d.windowStart(key, value, window)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("SELECT COALESCE(SUM(count), 0) FROM events WHERE %s = ? AND timestamp >= ?", column)
+	args := []interface{}{value, since}
+	if rule != "" {
+		query += " AND rule = ?"
+		args = append(args, rule)
+	}
+	var count int
+	if err := d.conn.QueryRow(query, args...).Scan(&count); err != nil {
+		log.Printf("Error counting events for %s %s: %v", key, value, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountDistinctRules - повертає кількість різних правил у подіях зі значенням ключа кореляції за період
+func (d *Database) CountDistinctRules(key, value string, window time.Duration) (int, error) {
+	column, ok := keyColumns[key]
+	if !ok {
+		return 0, fmt.Errorf("unknown correlation key %q", key)
+	}
+	since, err := d.windowStart(key, value, window)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(DISTINCT rule) FROM events WHERE %s = ? AND timestamp >= ?", column)
+	if err := d.conn.QueryRow(query, value, since).Scan(&count); err != nil {
+		log.Printf("Error counting distinct rules for %s %s: %v", key, value, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// PruneEvents - видаляє з журналу події, старші за вказаний час
+func (d *Database) PruneEvents(before time.Time) (int64, error) {
+	res, err := d.conn.Exec("DELETE FROM events WHERE timestamp < ?", before.UTC())
+	if err != nil {
+		log.Printf("Error pruning events: %v", err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordEventTimestamp(t *testing.T) {
+	tests := []struct {
+		name      string
+		offset    time.Duration
+		count     int
+		window    time.Duration
+		wantCount int
+		pruned    bool // Запис видаляється очищенням журналу до моменту трохи пізніше за поточний
+	}{
+		{name: "current", offset: 0, window: time.Hour, wantCount: 1},
+		{name: "future dated is recorded as now", offset: 24 * time.Hour, window: time.Hour, wantCount: 1, pruned: true},
+		{name: "backdated outside window", offset: -2 * time.Hour, window: time.Hour, wantCount: 0},
+		{name: "rate limit summary counts aggregated events", count: 5, window: time.Hour, wantCount: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDatabase(t)
+			if _, err := d.RecordEvent(EventRecord{IP: "203.0.113.7", Rule: "r", Source: "s", Timestamp: time.Now().Add(tt.offset), Count: tt.count}); err != nil {
+				t.Fatalf("RecordEvent: %v", err)
+			}
+			if n, _ := d.CountEventsByKey("ip", "203.0.113.7", "", tt.window); n != tt.wantCount {
+				t.Errorf("count in %s window = %d, want %d", tt.window, n, tt.wantCount)
+			}
+			if tt.pruned {
+				if n, _ := d.PruneEvents(time.Now().Add(time.Minute)); n != 1 {
+					t.Errorf("PruneEvents removed %d rows, want the future-dated row", n)
+				}
+			}
+		})
+	}
+}
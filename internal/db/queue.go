@@ -0,0 +1,156 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+)
+
+// Етапи обробки події з черги
+const (
+	StageReceived = "received" // Подію ще не зіставлено зі сценаріями
+	StageActing   = "acting"   // Сценарії оцінено; лишилося виконати збережених діячів
+)
+
+// QueuedEvent - подія з черги, що ще не оброблена до кінця
+type QueuedEvent struct {
+	ID       int64
+	Payload  []byte
+	Attempts int
+	Stage    string
+	Actions  []byte // Для StageActing - діячі, що ще не виконалися успішно
+}
+
+// createQueueTable - створює таблицю черги подій
+func (d *Database) createQueueTable() error {
+	_, err := d.conn.Exec(`
+        CREATE TABLE IF NOT EXISTS event_queue (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            payload TEXT NOT NULL,
+            status TEXT NOT NULL DEFAULT 'pending',
+            attempts INTEGER NOT NULL DEFAULT 0,
+            enqueued_at DATETIME NOT NULL,
+            last_error TEXT
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
+	// Колонки етапу обробки додаються і до черг, створених раніше
+	for _, column := range []string{
+		"stage TEXT NOT NULL DEFAULT '" + StageReceived + "'",
+		"actions TEXT",
+	} {
+		_, err := d.conn.Exec("ALTER TABLE event_queue ADD COLUMN " + column)
+		if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnqueueEvents - зберігає події в черзі однією транзакцією; повертає їхні ідентифікатори
+func (d *Database) EnqueueEvents(payloads [][]byte, timestamp time.Time) ([]int64, error) {
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO event_queue (payload, enqueued_at) VALUES (?, ?)")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	ids := make([]int64, 0, len(payloads))
+	for _, p := range payloads {
+		res, err := stmt.Exec(string(p), timestamp.UTC())
+		if err != nil {
+			log.Printf("Error enqueueing event: %v", err)
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, tx.Commit()
+}
+
+// StartEvent - фіксує спробу обробки події
+func (d *Database) StartEvent(id int64) error {
+	_, err := d.conn.Exec("UPDATE event_queue SET attempts = attempts + 1 WHERE id = ?", id)
+	if err != nil {
+		log.Printf("Error starting queued event %d: %v", id, err)
+	}
+	return err
+}
+
+// SaveEventActions - переводить подію на етап StageActing і зберігає діячів, що лишилися
+func (d *Database) SaveEventActions(id int64, actions []byte) error {
+	_, err := d.conn.Exec("UPDATE event_queue SET stage = ?, actions = ? WHERE id = ?", StageActing, string(actions), id)
+	if err != nil {
+		log.Printf("Error saving actions of queued event %d: %v", id, err)
+	}
+	return err
+}
+
+// CompleteEvents - видаляє оброблені події з черги
+func (d *Database) CompleteEvents(ids ...int64) error {
+	for _, id := range ids {
+		if _, err := d.conn.Exec("DELETE FROM event_queue WHERE id = ?", id); err != nil {
+			log.Printf("Error completing queued event %d: %v", id, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// FailEvent - позначає подію як невдалу; вона більше не повторюватиметься
+func (d *Database) FailEvent(id int64, reason string) error {
+	_, err := d.conn.Exec("UPDATE event_queue SET status = 'failed', last_error = ? WHERE id = ?", reason, id)
+	if err != nil {
+		log.Printf("Error failing queued event %d: %v", id, err)
+	}
+	return err
+}
+
+// PendingEvents - повертає необроблені події в порядку надходження
+func (d *Database) PendingEvents() ([]QueuedEvent, error) {
+	rows, err := d.conn.Query("SELECT id, payload, attempts, stage, actions FROM event_queue WHERE status = 'pending' ORDER BY id")
+	if err != nil {
+		log.Printf("Error querying queued events: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []QueuedEvent
+	for rows.Next() {
+		var e QueuedEvent
+		var payload string
+		var actions sql.NullString
+		if err := rows.Scan(&e.ID, &payload, &e.Attempts, &e.Stage, &actions); err != nil {
+			return nil, err
+		}
+		e.Payload = []byte(payload)
+		if actions.Valid {
+			e.Actions = []byte(actions.String)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// PruneFailedEvents - видаляє з черги невдалі події, додані раніше за before
+func (d *Database) PruneFailedEvents(before time.Time) (int64, error) {
+	res, err := d.conn.Exec("DELETE FROM event_queue WHERE status = 'failed' AND enqueued_at < ?", before.UTC())
+	if err != nil {
+		log.Printf("Error pruning failed queued events: %v", err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// SequenceState - стан частково пройденої послідовності для ключа кореляції
+type SequenceState struct {
+	Step    int       // Кількість уже пройдених кроків
+	Started time.Time // Час події першого кроку
+	Updated time.Time
+}
+
+// createSequencesTable - створює таблицю стану послідовних сценаріїв
+func (d *Database) createSequencesTable() error {
+	_, err := d.conn.Exec(`
+        CREATE TABLE IF NOT EXISTS sequence_state (
+            scenario TEXT NOT NULL,
+            key TEXT NOT NULL,
+            step INTEGER NOT NULL,
+            started DATETIME NOT NULL,
+            updated DATETIME NOT NULL,
+            PRIMARY KEY (scenario, key)
+        )
+    `)
+	return err
+}
+
+// GetSequence - повертає стан послідовності; false, якщо її ще не розпочато
+func (d *Database) GetSequence(scenario, key string) (SequenceState, bool, error) {
+	var st SequenceState
+	err := d.conn.QueryRow("SELECT step, started, updated FROM sequence_state WHERE scenario = ? AND key = ?", scenario, key).
+		Scan(&st.Step, &st.Started, &st.Updated)
+	if err == sql.ErrNoRows {
+		return st, false, nil
+	}
+	if err != nil {
+		log.Printf("Error getting sequence state %s/%s: %v", scenario, key, err)
+		return st, false, err
+	}
+	return st, true, nil
+}
+
+// SaveSequence - зберігає стан послідовності
+func (d *Database) SaveSequence(scenario, key string, st SequenceState) error {
+	_, err := d.conn.Exec(`
+        INSERT INTO sequence_state (scenario, key, step, started, updated)
+        VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT (scenario, key) DO UPDATE SET step = excluded.step, started = excluded.started, updated = excluded.updated
+    `, scenario, key, st.Step, st.Started.UTC(), st.Updated.UTC())
+	if err != nil {
+		log.Printf("Error saving sequence state %s/%s: %v", scenario, key, err)
+	}
+	return err
+}
+
+// DeleteSequence - скидає стан послідовності
+func (d *Database) DeleteSequence(scenario, key string) error {
+	_, err := d.conn.Exec("DELETE FROM sequence_state WHERE scenario = ? AND key = ?", scenario, key)
+	if err != nil {
+		log.Printf("Error deleting sequence state %s/%s: %v", scenario, key, err)
+	}
+	return err
+}
+
+// PruneSequences - видаляє стани послідовностей, розпочаті раніше вказаного часу
+func (d *Database) PruneSequences(before time.Time) (int64, error) {
+	res, err := d.conn.Exec("DELETE FROM sequence_state WHERE started < ?", before.UTC())
+	if err != nil {
+		log.Printf("Error pruning sequence state: %v", err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,59 @@
+package decoder
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+)
+
+// Decoder - інтерфейс для декодерів вхідних подій
+type Decoder interface {
+	Decode(data []byte) ([]actioner.Event, error)
+	Name() string
+}
+
+// Config - конфігурація декодера
+type Config struct {
+	Type   string                 `mapstructure:"type"`
+	Params map[string]interface{} `mapstructure:"params"`
+}
+
+// Factory - функція, що створює декодер за конфігурацією
+type Factory func(cfg Config) (Decoder, error)
+
+// DefaultType - тип декодера, який використовується, якщо тип не вказано
+const DefaultType = "generic"
+
+var registry = map[string]Factory{}
+
+// Register - реєструє новий тип декодера
+func Register(typ string, factory Factory) {
+	if _, exists := registry[typ]; exists {
+		panic(fmt.Sprintf("decoder type %q registered twice", typ))
+	}
+	registry[typ] = factory
+}
+
+// Types - повертає список зареєстрованих типів декодерів
+func Types() []string {
+	types := make([]string, 0, len(registry))
+	for typ := range registry {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// New - створює декодер за конфігурацією
+func New(cfg Config) (Decoder, error) {
+	typ := cfg.Type
+	if typ == "" {
+		typ = DefaultType
+	}
+	factory, ok := registry[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown decoder type %q (available: %v)", typ, Types())
+	}
+	return factory(cfg)
+}
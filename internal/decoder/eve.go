@@ -0,0 +1,203 @@
+package decoder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+)
+
+func init() {
+	Register("eve", func(cfg Config) (Decoder, error) {
+		return NewEveDecoder(cfg.Params)
+	})
+}
+
+// DefaultEveEventTypes - типи записів EVE, які обробляються за замовчуванням
+var DefaultEveEventTypes = []string{"alert", "anomaly", "http", "dns"}
+
+// eveTimeLayout - формат часу Suricata ("2023-11-02T10:00:00.123456+0000")
+const eveTimeLayout = "2006-01-02T15:04:05.999999-0700"
+
+// EveRecord - запис Suricata eve.json
+type EveRecord struct {
+	Timestamp string      `json:"timestamp"`
+	FlowID    json.Number `json:"flow_id"`
+	InIface   string      `json:"in_iface"`
+	EventType string      `json:"event_type"`
+	SrcIP     string      `json:"src_ip"`
+	SrcPort   int         `json:"src_port"`
+	DestIP    string      `json:"dest_ip"`
+	DestPort  int         `json:"dest_port"`
+	Proto     string      `json:"proto"`
+	AppProto  string      `json:"app_proto"`
+	Host      string      `json:"host"`
+	Alert     *struct {
+		Action      string `json:"action"`
+		SignatureID int64  `json:"signature_id"`
+		Rev         int    `json:"rev"`
+		Signature   string `json:"signature"`
+		Category    string `json:"category"`
+		Severity    int    `json:"severity"`
+	} `json:"alert"`
+	Anomaly *struct {
+		Type  string `json:"type"`
+		Event string `json:"event"`
+		Layer string `json:"layer"`
+	} `json:"anomaly"`
+	HTTP *struct {
+		Hostname  string `json:"hostname"`
+		URL       string `json:"url"`
+		UserAgent string `json:"http_user_agent"`
+		Method    string `json:"http_method"`
+		Status    int    `json:"status"`
+	} `json:"http"`
+	DNS *struct {
+		Type   string `json:"type"`
+		RRName string `json:"rrname"`
+		RRType string `json:"rrtype"`
+		RCode  string `json:"rcode"`
+	} `json:"dns"`
+}
+
+// EveDecoder - декодер записів Suricata EVE JSON (окремих і NDJSON-пакетів)
+type EveDecoder struct {
+	eventTypes map[string]bool
+}
+
+// NewEveDecoder - створює новий EveDecoder
+func NewEveDecoder(params map[string]interface{}) (*EveDecoder, error) {
+	types, err := stringsParam(params, "event_types")
+	if err != nil {
+		return nil, err
+	}
+	if len(types) == 0 {
+		types = DefaultEveEventTypes
+	}
+	ed := &EveDecoder{eventTypes: make(map[string]bool, len(types))}
+	for _, t := range types {
+		ed.eventTypes[t] = true
+	}
+	return ed, nil
+}
+
+// Decode - розбирає один запис EVE або пакет записів, розділених новими рядками
+func (ed *EveDecoder) Decode(data []byte) ([]actioner.Event, error) {
+	var events []actioner.Event
+	var lines, failed int
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		lines++
+		var rec EveRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			failed++
+			log.Printf("Skipping invalid EVE record on line %d: %v", lines, err)
+			continue
+		}
+		if !ed.eventTypes[rec.EventType] {
+			continue
+		}
+		events = append(events, ed.Event(&rec, string(line)))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read EVE records: %v", err)
+	}
+	if lines > 0 && failed == lines {
+		return nil, fmt.Errorf("invalid EVE records: all %d lines failed to parse", lines)
+	}
+	return events, nil
+}
+
+// Event - перетворює запис EVE на подію
+func (ed *EveDecoder) Event(rec *EveRecord, raw string) actioner.Event {
+	event := actioner.Event{
+		IP:       rec.SrcIP,
+		RuleName: rec.EventType,
+		Log:      raw,
+		Priority: "Informational",
+		Hostname: rec.Host,
+		Fields: map[string]string{
+			"eve.event_type": rec.EventType,
+			"src_ip":         rec.SrcIP,
+			"src_port":       strconv.Itoa(rec.SrcPort),
+			"dest_ip":        rec.DestIP,
+			"dest_port":      strconv.Itoa(rec.DestPort),
+			"proto":          rec.Proto,
+		},
+	}
+	if t, err := time.Parse(eveTimeLayout, rec.Timestamp); err == nil {
+		event.Time = t
+	} else if t, err := time.Parse(time.RFC3339Nano, rec.Timestamp); err == nil {
+		event.Time = t
+	}
+	if rec.FlowID != "" {
+		event.Fields["flow_id"] = rec.FlowID.String()
+	}
+	if rec.AppProto != "" {
+		event.Fields["app_proto"] = rec.AppProto
+	}
+	if rec.InIface != "" {
+		event.Fields["in_iface"] = rec.InIface
+	}
+
+	switch {
+	case rec.Alert != nil:
+		a := rec.Alert
+		event.RuleName = a.Signature
+		event.Priority = eveSeverityPriority(a.Severity)
+		event.Fields["alert.signature"] = a.Signature
+		event.Fields["alert.signature_id"] = strconv.FormatInt(a.SignatureID, 10)
+		event.Fields["alert.rev"] = strconv.Itoa(a.Rev)
+		event.Fields["alert.severity"] = strconv.Itoa(a.Severity)
+		event.Fields["alert.category"] = a.Category
+		event.Fields["alert.action"] = a.Action
+		if a.Category != "" {
+			event.Tags = []string{a.Category}
+		}
+	case rec.Anomaly != nil:
+		event.RuleName = rec.Anomaly.Event
+		event.Priority = "Notice"
+		event.Fields["anomaly.type"] = rec.Anomaly.Type
+		event.Fields["anomaly.event"] = rec.Anomaly.Event
+		event.Fields["anomaly.layer"] = rec.Anomaly.Layer
+	case rec.HTTP != nil:
+		event.Fields["http.hostname"] = rec.HTTP.Hostname
+		event.Fields["http.url"] = rec.HTTP.URL
+		event.Fields["http.user_agent"] = rec.HTTP.UserAgent
+		event.Fields["http.method"] = rec.HTTP.Method
+		event.Fields["http.status"] = strconv.Itoa(rec.HTTP.Status)
+	case rec.DNS != nil:
+		event.Fields["dns.type"] = rec.DNS.Type
+		event.Fields["dns.rrname"] = rec.DNS.RRName
+		event.Fields["dns.rrtype"] = rec.DNS.RRType
+		event.Fields["dns.rcode"] = rec.DNS.RCode
+	}
+	return event
+}
+
+// Name - повертає ім'я декодера
+func (ed *EveDecoder) Name() string { return "eve" }
+
+// eveSeverityPriority - перетворює severity Suricata (1 - найвища) на пріоритет у стилі Falco
+func eveSeverityPriority(severity int) string {
+	switch severity {
+	case 1:
+		return "Critical"
+	case 2:
+		return "Error"
+	case 3:
+		return "Warning"
+	default:
+		return "Notice"
+	}
+}
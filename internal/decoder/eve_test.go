@@ -0,0 +1,122 @@
+package decoder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEveDecoder(t *testing.T) {
+	type want struct {
+		ip     string
+		rule   string
+		fields map[string]string
+	}
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		data    []byte
+		want    []want
+		wantErr bool
+	}{
+		{
+			// flow і stats не входять до типів за замовчуванням, порожній рядок пропускається
+			name: "ndjson batch with default types",
+			data: readSample(t, "eve_batch.ndjson"),
+			want: []want{
+				{
+					ip:   "198.51.100.61",
+					rule: "ET SCAN Potential SSH Scan",
+					fields: map[string]string{
+						"alert.signature_id": "2001219",
+						"alert.category":     "Attempted Information Leak",
+						"dest_port":          "22",
+						"flow_id":            "1804352187613401",
+					},
+				},
+				{
+					ip:     "10.0.3.20",
+					rule:   "dns",
+					fields: map[string]string{"dns.rrname": "xmr.pool.minergate.com", "dns.rrtype": "A"},
+				},
+			},
+		},
+		{
+			name:   "configured event types",
+			params: map[string]interface{}{"event_types": []interface{}{"flow"}},
+			data:   readSample(t, "eve_batch.ndjson"),
+			want:   []want{{ip: "10.0.3.20", rule: "flow", fields: map[string]string{"app_proto": "dns"}}},
+		},
+		{
+			name: "anomaly",
+			data: []byte(`{"timestamp":"2024-05-01T10:26:00.000000+0000","event_type":"anomaly","src_ip":"203.0.113.5","anomaly":{"type":"applayer","event":"APPLAYER_DETECT_PROTOCOL_ONLY_ONE_DIRECTION","layer":"proto_detect"}}`),
+			want: []want{{ip: "203.0.113.5", rule: "APPLAYER_DETECT_PROTOCOL_ONLY_ONE_DIRECTION", fields: map[string]string{"anomaly.layer": "proto_detect"}}},
+		},
+		{
+			// Запис без src_ip дає подію без IP
+			name: "missing ip",
+			data: []byte(`{"event_type":"alert","alert":{"signature":"GPL ICMP_INFO PING","severity":3}}`),
+			want: []want{{rule: "GPL ICMP_INFO PING"}},
+		},
+		{
+			name: "invalid line skipped",
+			data: []byte("{\"event_type\":\"dns\",\"src_ip\":\"10.0.0.1\"}\n{\"event_type\":\n"),
+			want: []want{{ip: "10.0.0.1", rule: "dns"}},
+		},
+		{name: "all lines filtered", data: []byte(`{"event_type":"stats","stats":{"uptime":1}}`)},
+		{name: "empty body", data: []byte("\n\n")},
+		{name: "malformed json", data: []byte(`{"event_type":`), wantErr: true},
+		{name: "all lines invalid", data: []byte("not json\n{\"src_port\":\"22\"}\n"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ed, err := NewEveDecoder(tt.params)
+			if err != nil {
+				t.Fatalf("NewEveDecoder: %v", err)
+			}
+			events, err := ed.Decode(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(events), len(tt.want))
+			}
+			for i, w := range tt.want {
+				e := events[i]
+				if e.IP != w.ip || e.RuleName != w.rule {
+					t.Errorf("event %d: IP, RuleName = %q, %q; want %q, %q", i, e.IP, e.RuleName, w.ip, w.rule)
+				}
+				for k, want := range w.fields {
+					if got := e.Field(k); got != want {
+						t.Errorf("event %d: Field(%q) = %q, want %q", i, k, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEveDecoderAlertMetadata(t *testing.T) {
+	ed, err := NewEveDecoder(nil)
+	if err != nil {
+		t.Fatalf("NewEveDecoder: %v", err)
+	}
+	events, err := ed.Decode(readSample(t, "eve_batch.ndjson"))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	e := events[0]
+	if want := time.Date(2024, 5, 1, 10, 25, 41, 822034000, time.UTC); !e.Time.Equal(want) {
+		t.Errorf("Time = %s, want %s", e.Time, want)
+	}
+	if e.Priority != "Error" || len(e.Tags) != 1 || e.Tags[0] != "Attempted Information Leak" {
+		t.Errorf("Priority, Tags = %q, %v", e.Priority, e.Tags)
+	}
+}
+
+func TestEveSeverityPriority(t *testing.T) {
+	for severity, want := range map[int]string{1: "Critical", 2: "Error", 3: "Warning", 4: "Notice", 0: "Notice"} {
+		if got := eveSeverityPriority(severity); got != want {
+			t.Errorf("eveSeverityPriority(%d) = %q, want %q", severity, got, want)
+		}
+	}
+}
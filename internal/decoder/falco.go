@@ -0,0 +1,125 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+)
+
+func init() {
+	Register("falco", func(cfg Config) (Decoder, error) {
+		ipFields, err := stringsParam(cfg.Params, "ip_fields")
+		if err != nil {
+			return nil, err
+		}
+		return NewFalcoDecoder(ipFields), nil
+	})
+}
+
+// DefaultFalcoIPFields - поля output_fields, з яких за замовчуванням береться IP
+var DefaultFalcoIPFields = []string{"fd.rip", "fd.cip", "fd.sip"}
+
+// FalcoAlert - сповіщення Falco у форматі JSON (json_output, http_output, falcosidekick)
+type FalcoAlert struct {
+	Rule         string                 `json:"rule"`
+	Priority     string                 `json:"priority"`
+	Time         string                 `json:"time"`
+	Output       string                 `json:"output"`
+	OutputFields map[string]interface{} `json:"output_fields"`
+	Tags         []string               `json:"tags"`
+	Hostname     string                 `json:"hostname"`
+	Source       string                 `json:"source"`
+
+	// Поля власного формату події, які підтримуються для зворотної сумісності
+	IP  string `json:"ip"`
+	Log string `json:"log"`
+}
+
+// FalcoDecoder - декодер сповіщень Falco
+type FalcoDecoder struct {
+	ipFields []string
+}
+
+// NewFalcoDecoder - створює новий FalcoDecoder
+func NewFalcoDecoder(ipFields []string) *FalcoDecoder {
+	if len(ipFields) == 0 {
+		ipFields = DefaultFalcoIPFields
+	}
+	return &FalcoDecoder{ipFields: ipFields}
+}
+
+// Decode - розбирає JSON-сповіщення Falco у подію
+func (fd *FalcoDecoder) Decode(data []byte) ([]actioner.Event, error) {
+	var alert FalcoAlert
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // Зберігаємо точність великих чисел (evt.time у наносекундах)
+	if err := dec.Decode(&alert); err != nil {
+		return nil, fmt.Errorf("invalid falco alert: %v", err)
+	}
+	return []actioner.Event{fd.Event(alert)}, nil
+}
+
+// Name - повертає ім'я декодера
+func (fd *FalcoDecoder) Name() string { return "falco" }
+
+// Event - перетворює сповіщення Falco на подію
+func (fd *FalcoDecoder) Event(alert FalcoAlert) actioner.Event {
+	event := actioner.Event{
+		IP:       alert.IP,
+		RuleName: alert.Rule,
+		Log:      alert.Log,
+		Priority: alert.Priority,
+		Hostname: alert.Hostname,
+		Tags:     alert.Tags,
+	}
+	if event.Log == "" {
+		event.Log = alert.Output
+	}
+	if alert.Time != "" {
+		if t, err := time.Parse(time.RFC3339Nano, alert.Time); err == nil {
+			event.Time = t
+		}
+	}
+
+	if len(alert.OutputFields) > 0 {
+		event.Fields = make(map[string]string, len(alert.OutputFields)+1)
+		for k, v := range alert.OutputFields {
+			if v == nil {
+				continue
+			}
+			switch val := v.(type) {
+			case string:
+				event.Fields[k] = val
+			case json.Number:
+				event.Fields[k] = val.String()
+			case float64:
+				event.Fields[k] = strconv.FormatFloat(val, 'f', -1, 64)
+			default:
+				event.Fields[k] = fmt.Sprint(val)
+			}
+		}
+	}
+	if alert.Source != "" {
+		if event.Fields == nil {
+			event.Fields = make(map[string]string, 1)
+		}
+		if _, ok := event.Fields["evt.source"]; !ok {
+			event.Fields["evt.source"] = alert.Source
+		}
+	}
+
+	if event.IP == "" {
+		for _, key := range fd.ipFields {
+			if ip := event.Field(key); net.ParseIP(ip) != nil {
+				event.IP = ip
+				break
+			}
+		}
+	}
+	return event
+}
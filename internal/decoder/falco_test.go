@@ -0,0 +1,115 @@
+package decoder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readSample - читає приклад повідомлення з testdata
+func readSample(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read sample: %v", err)
+	}
+	return data
+}
+
+func TestFalcoDecoder(t *testing.T) {
+	tests := []struct {
+		name       string
+		ipFields   []string
+		data       []byte
+		wantIP     string
+		wantRule   string
+		wantFields map[string]string
+		wantErr    bool
+	}{
+		{
+			name:     "http_output alert",
+			data:     readSample(t, "falco_http_output.json"),
+			wantIP:   "198.51.100.23", // fd.rip - перше з полів за замовчуванням
+			wantRule: "Outbound Connection to C2 Servers",
+			wantFields: map[string]string{
+				"container.id": "4f9c2a1b7d3e",
+				"evt.time":     "1714558362154337421", // Без втрати точності
+				"proc.pid":     "4242",
+				"user.uid":     "0",
+				"evt.source":   "syscall",
+			},
+		},
+		{
+			name:     "custom ip_fields",
+			ipFields: []string{"fd.cip"},
+			data:     readSample(t, "falco_http_output.json"),
+			wantIP:   "10.8.0.14",
+			wantRule: "Outbound Connection to C2 Servers",
+		},
+		{
+			name:     "ip field that is not an address skipped",
+			data:     []byte(`{"rule":"r","output_fields":{"fd.rip":"<NA>","fd.cip":"10.0.0.5"}}`),
+			wantIP:   "10.0.0.5",
+			wantRule: "r",
+		},
+		{
+			name:     "legacy format",
+			data:     []byte(`{"ip":"203.0.113.7","rule":"Suspicious","log":"custom"}`),
+			wantIP:   "203.0.113.7",
+			wantRule: "Suspicious",
+		},
+		{
+			// Подія без IP не помилка: сценарії з іншим ключем кореляції обробляють і її
+			name:       "missing ip",
+			data:       []byte(`{"rule":"Terminal shell in container","source":"syscall","output_fields":{"container.id":"abc"}}`),
+			wantRule:   "Terminal shell in container",
+			wantFields: map[string]string{"container.id": "abc", "evt.source": "syscall"},
+		},
+		{name: "malformed json", data: []byte(`{"rule":`), wantErr: true},
+		{name: "not an object", data: []byte(`["rule"]`), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := NewFalcoDecoder(tt.ipFields).Decode(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+			e := events[0]
+			if e.IP != tt.wantIP || e.RuleName != tt.wantRule {
+				t.Errorf("IP, RuleName = %q, %q; want %q, %q", e.IP, e.RuleName, tt.wantIP, tt.wantRule)
+			}
+			for k, want := range tt.wantFields {
+				if got := e.Field(k); got != want {
+					t.Errorf("Field(%q) = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFalcoDecoderAlertMetadata(t *testing.T) {
+	events, err := NewFalcoDecoder(nil).Decode(readSample(t, "falco_http_output.json"))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	e := events[0]
+	if want := time.Date(2024, 5, 1, 10, 12, 42, 154337421, time.UTC); !e.Time.Equal(want) {
+		t.Errorf("Time = %s, want %s", e.Time, want)
+	}
+	if e.Priority != "Warning" || e.Hostname != "gke-prod-pool-1-8c2e" || len(e.Tags) != 4 {
+		t.Errorf("Priority, Hostname, Tags = %q, %q, %v", e.Priority, e.Hostname, e.Tags)
+	}
+	if e.Log == "" {
+		t.Errorf("output not used as log")
+	}
+	if _, ok := e.Fields["container.privileged"]; ok {
+		t.Errorf("null output field kept")
+	}
+}
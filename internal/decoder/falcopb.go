@@ -0,0 +1,153 @@
+package decoder
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// falcoPriorities - значення enum falco.schema.priority
+var falcoPriorities = []string{"Emergency", "Alert", "Critical", "Error", "Warning", "Notice", "Informational", "Debug"}
+
+// falcoSources - значення застарілого enum falco.schema.source
+var falcoSources = []string{"syscall", "k8s_audit", "internal", "plugins"}
+
+// ParseFalcoOutput - розбирає повідомлення falco.outputs.response (gRPC outputs service) у сповіщення Falco
+func ParseFalcoOutput(data []byte) (FalcoAlert, error) {
+	var alert FalcoAlert
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return alert, fmt.Errorf("invalid falco output: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		switch {
+		case num == 1 && typ == protowire.BytesType: // time
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return alert, fmt.Errorf("invalid falco output time: %v", protowire.ParseError(n))
+			}
+			t, err := parseProtoTimestamp(v)
+			if err != nil {
+				return alert, err
+			}
+			alert.Time = t.UTC().Format(time.RFC3339Nano)
+			data = data[n:]
+		case (num == 2 || num == 3) && typ == protowire.VarintType: // priority, source_deprecated
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return alert, fmt.Errorf("invalid falco output enum: %v", protowire.ParseError(n))
+			}
+			if num == 2 && v < uint64(len(falcoPriorities)) {
+				alert.Priority = falcoPriorities[v]
+			}
+			if num == 3 && alert.Source == "" && v < uint64(len(falcoSources)) {
+				alert.Source = falcoSources[v]
+			}
+			data = data[n:]
+		case num == 6 && typ == protowire.BytesType: // output_fields
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return alert, fmt.Errorf("invalid falco output field: %v", protowire.ParseError(n))
+			}
+			key, value, err := parseProtoMapEntry(v)
+			if err != nil {
+				return alert, err
+			}
+			if alert.OutputFields == nil {
+				alert.OutputFields = make(map[string]interface{})
+			}
+			alert.OutputFields[key] = value
+			data = data[n:]
+		case typ == protowire.BytesType && (num == 4 || num == 5 || num == 7 || num == 8 || num == 9):
+			v, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return alert, fmt.Errorf("invalid falco output string: %v", protowire.ParseError(n))
+			}
+			switch num {
+			case 4:
+				alert.Rule = v
+			case 5:
+				alert.Output = v
+			case 7:
+				alert.Hostname = v
+			case 8:
+				alert.Tags = append(alert.Tags, v)
+			case 9:
+				alert.Source = v
+			}
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return alert, fmt.Errorf("invalid falco output: %v", protowire.ParseError(n))
+			}
+			data = data[n:]
+		}
+	}
+	return alert, nil
+}
+
+// parseProtoTimestamp - розбирає google.protobuf.Timestamp
+func parseProtoTimestamp(data []byte) (time.Time, error) {
+	var seconds, nanos int64
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return time.Time{}, fmt.Errorf("invalid timestamp: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+		if typ == protowire.VarintType && (num == 1 || num == 2) {
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return time.Time{}, fmt.Errorf("invalid timestamp: %v", protowire.ParseError(n))
+			}
+			if num == 1 {
+				seconds = int64(v)
+			} else {
+				nanos = int64(v)
+			}
+			data = data[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, data)
+		if n < 0 {
+			return time.Time{}, fmt.Errorf("invalid timestamp: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+	}
+	return time.Unix(seconds, nanos), nil
+}
+
+// parseProtoMapEntry - розбирає елемент map<string, string>
+func parseProtoMapEntry(data []byte) (string, string, error) {
+	var key, value string
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return "", "", fmt.Errorf("invalid map entry: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+		if typ == protowire.BytesType && (num == 1 || num == 2) {
+			v, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return "", "", fmt.Errorf("invalid map entry: %v", protowire.ParseError(n))
+			}
+			if num == 1 {
+				key = v
+			} else {
+				value = v
+			}
+			data = data[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, data)
+		if n < 0 {
+			return "", "", fmt.Errorf("invalid map entry: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+	}
+	return key, value, nil
+}
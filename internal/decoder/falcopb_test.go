@@ -0,0 +1,113 @@
+package decoder
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// pbString - поле типу string/bytes у wire-форматі protobuf
+func pbString(num protowire.Number, v string) []byte {
+	b := protowire.AppendTag(nil, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// pbBytes - вкладене повідомлення у wire-форматі protobuf
+func pbBytes(num protowire.Number, v []byte) []byte {
+	b := protowire.AppendTag(nil, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// pbVarint - поле типу varint у wire-форматі protobuf
+func pbVarint(num protowire.Number, v uint64) []byte {
+	b := protowire.AppendTag(nil, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func TestParseFalcoOutput(t *testing.T) {
+	timestamp := concat(pbVarint(1, 1700000000), pbVarint(2, 5))
+	full := concat(
+		pbBytes(1, timestamp),
+		pbVarint(2, 4), // Warning
+		pbString(4, "Terminal shell in container"),
+		pbString(5, "A shell was spawned"),
+		pbBytes(6, concat(pbString(1, "fd.sip"), pbString(2, "10.0.0.5"))),
+		pbBytes(6, concat(pbString(1, "container.id"), pbString(2, "abc123"))),
+		pbString(7, "node-1"),
+		pbString(8, "container"),
+		pbString(8, "shell"),
+		pbString(9, "syscall"),
+	)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    FalcoAlert
+		wantErr bool
+	}{
+		{
+			name: "full",
+			data: full,
+			want: FalcoAlert{
+				Rule:         "Terminal shell in container",
+				Priority:     "Warning",
+				Time:         "2023-11-14T22:13:20.000000005Z",
+				Output:       "A shell was spawned",
+				OutputFields: map[string]interface{}{"fd.sip": "10.0.0.5", "container.id": "abc123"},
+				Tags:         []string{"container", "shell"},
+				Hostname:     "node-1",
+				Source:       "syscall",
+			},
+		},
+		{name: "empty", data: nil, want: FalcoAlert{}},
+		{
+			name: "deprecated source enum",
+			data: concat(pbVarint(3, 1), pbString(4, "K8s event")),
+			want: FalcoAlert{Rule: "K8s event", Source: "k8s_audit"},
+		},
+		{
+			name: "source string wins over deprecated enum",
+			data: concat(pbString(9, "plugins"), pbVarint(3, 0)),
+			want: FalcoAlert{Source: "plugins"},
+		},
+		{
+			name: "unknown fields and enum values are skipped",
+			data: concat(pbVarint(2, 99), pbString(42, "future"), pbVarint(43, 7), pbString(4, "r")),
+			want: FalcoAlert{Rule: "r"},
+		},
+		{name: "truncated tag", data: []byte{0x80}, wantErr: true},
+		{name: "truncated unknown fixed64", data: append(protowire.AppendTag(nil, 43, protowire.Fixed64Type), 1, 2), wantErr: true},
+		{name: "truncated string", data: pbString(4, "Terminal shell")[:5], wantErr: true},
+		{name: "truncated varint", data: []byte{0x10, 0x80}, wantErr: true},
+		{name: "truncated timestamp", data: pbBytes(1, []byte{0x08, 0x80}), wantErr: true},
+		{name: "truncated output field", data: pbBytes(6, pbString(1, "fd.sip")[:3]), wantErr: true},
+		{name: "wrong wire type for rule", data: concat(pbVarint(4, 1)), want: FalcoAlert{}},
+		{name: "truncated after valid field", data: full[:len(full)-3], wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFalcoOutput(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
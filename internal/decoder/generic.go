@@ -0,0 +1,152 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+)
+
+func init() {
+	Register("generic", func(cfg Config) (Decoder, error) { return NewGenericDecoder(cfg.Params) })
+}
+
+// genericFields - поля події, які можна зіставити зі шляхами у JSON
+var genericFields = []string{"ip", "rule", "log", "severity", "time", "hostname"}
+
+// GenericDecoder - декодер власного формату події {ip, rule, log}
+// або довільного JSON за налаштованим зіставленням полів
+type GenericDecoder struct {
+	fields   map[string]jsonPath // Поле події -> шлях у JSON
+	extra    map[string]jsonPath // Додаткові поля -> шлях у JSON
+	ruleName string              // Ім'я правила, якщо шлях rule не задано або порожній
+}
+
+// NewGenericDecoder - створює новий GenericDecoder
+func NewGenericDecoder(params map[string]interface{}) (*GenericDecoder, error) {
+	gd := &GenericDecoder{}
+	var err error
+	if gd.ruleName, err = stringParam(params, "rule_name", ""); err != nil {
+		return nil, err
+	}
+
+	fields, err := stringMapParam(params, "fields")
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > 0 {
+		gd.fields = make(map[string]jsonPath, len(fields))
+		for name, expr := range fields {
+			if !isGenericField(name) {
+				return nil, fmt.Errorf("unknown field %q in fields (supported: %v)", name, genericFields)
+			}
+			if gd.fields[name], err = compilePath(expr); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	extra, err := stringMapParam(params, "extra")
+	if err != nil {
+		return nil, err
+	}
+	if len(extra) > 0 {
+		gd.extra = make(map[string]jsonPath, len(extra))
+		for name, expr := range extra {
+			if gd.extra[name], err = compilePath(expr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return gd, nil
+}
+
+// Decode - розбирає подію у власному форматі або за зіставленням полів
+func (gd *GenericDecoder) Decode(data []byte) ([]actioner.Event, error) {
+	if gd.fields == nil && gd.extra == nil {
+		var event actioner.Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, fmt.Errorf("invalid event: %v", err)
+		}
+		if event.RuleName == "" {
+			event.RuleName = gd.ruleName
+		}
+		return []actioner.Event{event}, nil
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %v", err)
+	}
+
+	event := actioner.Event{
+		IP:       gd.fields["ip"].lookupString(doc),
+		RuleName: gd.fields["rule"].lookupString(doc),
+		Priority: gd.fields["severity"].lookupString(doc),
+		Hostname: gd.fields["hostname"].lookupString(doc),
+	}
+	if event.RuleName == "" {
+		event.RuleName = gd.ruleName
+	}
+	if path, ok := gd.fields["log"]; ok {
+		event.Log = path.lookupString(doc)
+	} else {
+		compact := new(bytes.Buffer)
+		if err := json.Compact(compact, data); err == nil {
+			event.Log = compact.String()
+		}
+	}
+	if ts := gd.fields["time"].lookupString(doc); ts != "" {
+		event.Time = parseTime(ts)
+	}
+
+	if len(gd.extra) > 0 {
+		event.Fields = make(map[string]string, len(gd.extra))
+		for name, path := range gd.extra {
+			if v := path.lookupString(doc); v != "" {
+				event.Fields[name] = v
+			}
+		}
+	}
+	return []actioner.Event{event}, nil
+}
+
+// Name - повертає ім'я декодера
+func (gd *GenericDecoder) Name() string { return "generic" }
+
+// isGenericField - перевіряє, чи підтримується поле у зіставленні
+func isGenericField(name string) bool {
+	for _, f := range genericFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTime - розбирає час у форматі RFC 3339 або Unix-час (секунди, мілі-, мікро- чи наносекунди)
+func parseTime(s string) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	switch {
+	case f > 1e17:
+		return time.Unix(0, int64(f))
+	case f > 1e14:
+		return time.UnixMicro(int64(f))
+	case f > 1e11:
+		return time.UnixMilli(int64(f))
+	default:
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9))
+	}
+}
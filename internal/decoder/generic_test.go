@@ -0,0 +1,172 @@
+package decoder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenericDecoder(t *testing.T) {
+	modsec := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"ip":       "$.transaction.client_ip",
+			"rule":     "$.transaction.messages[0].message",
+			"severity": "$.transaction.messages[0].details.severity",
+			"time":     "$.transaction.unix_timestamp",
+			"hostname": "$.transaction.request.headers.Host",
+		},
+		"extra": map[string]interface{}{
+			"rule.id":        "$.transaction.messages[0].details.ruleId",
+			"http.uri":       "$.transaction.request.uri",
+			"http.status":    "$.transaction.response.http_code",
+			"http.referer":   "$.transaction.request.headers.Referer", // Немає в події
+			"modsec.version": "$.transaction.producer.modsecurity",
+		},
+	}
+	tests := []struct {
+		name       string
+		params     map[string]interface{}
+		data       []byte
+		wantIP     string
+		wantRule   string
+		wantFields map[string]string
+		wantErr    bool
+	}{
+		{
+			name:     "mapped modsecurity audit log",
+			params:   modsec,
+			data:     readSample(t, "generic_modsecurity.json"),
+			wantIP:   "198.51.100.44",
+			wantRule: "SQL Injection Attack Detected via libinjection",
+			wantFields: map[string]string{
+				"rule.id":     "942100",
+				"http.uri":    "/products?id=1%27%20OR%201=1--",
+				"http.status": "403",
+			},
+		},
+		{
+			name:     "native format",
+			data:     []byte(`{"ip":"203.0.113.7","rule":"Suspicious","log":"custom","output_fields":{"user":"root"}}`),
+			wantIP:   "203.0.113.7",
+			wantRule: "Suspicious",
+			wantFields: map[string]string{
+				"user": "root",
+			},
+		},
+		{
+			name:     "native format default rule",
+			params:   map[string]interface{}{"rule_name": "Webhook"},
+			data:     []byte(`{"ip":"203.0.113.7"}`),
+			wantIP:   "203.0.113.7",
+			wantRule: "Webhook",
+		},
+		{
+			// Шлях ip не знайдено - подія без IP, а не помилка
+			name:     "mapped missing ip",
+			params:   map[string]interface{}{"fields": map[string]interface{}{"ip": "$.src"}, "rule_name": "Webhook"},
+			data:     []byte(`{"dst":"10.0.0.1"}`),
+			wantRule: "Webhook",
+		},
+		{
+			name:     "mapped ip from array",
+			params:   map[string]interface{}{"fields": map[string]interface{}{"ip": "$.alerts[1].ip", "rule": "$.name"}},
+			data:     []byte(`{"name":"Brute force","alerts":[{"ip":"10.0.0.1"},{"ip":"10.0.0.2"}]}`),
+			wantIP:   "10.0.0.2",
+			wantRule: "Brute force",
+		},
+		{name: "native malformed json", data: []byte(`{"ip":`), wantErr: true},
+		{name: "native wrong type", data: []byte(`{"ip":42}`), wantErr: true},
+		{name: "mapped malformed json", params: modsec, data: []byte(`{"transaction":`), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gd, err := NewGenericDecoder(tt.params)
+			if err != nil {
+				t.Fatalf("NewGenericDecoder: %v", err)
+			}
+			events, err := gd.Decode(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+			e := events[0]
+			if e.IP != tt.wantIP || e.RuleName != tt.wantRule {
+				t.Errorf("IP, RuleName = %q, %q; want %q, %q", e.IP, e.RuleName, tt.wantIP, tt.wantRule)
+			}
+			for k, want := range tt.wantFields {
+				if got := e.Field(k); got != want {
+					t.Errorf("Field(%q) = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenericDecoderMappedMetadata(t *testing.T) {
+	gd, err := NewGenericDecoder(map[string]interface{}{
+		"fields": map[string]interface{}{
+			"ip":       "$.transaction.client_ip",
+			"severity": "$.transaction.messages[0].details.severity",
+			"time":     "$.transaction.unix_timestamp",
+			"hostname": "$.transaction.request.headers.Host",
+		},
+		"extra": map[string]interface{}{"http.referer": "$.transaction.request.headers.Referer"},
+	})
+	if err != nil {
+		t.Fatalf("NewGenericDecoder: %v", err)
+	}
+	events, err := gd.Decode(readSample(t, "generic_modsecurity.json"))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	e := events[0]
+	if !e.Time.Equal(time.Unix(1714558925, 0)) || e.Priority != "2" || e.Hostname != "shop.example.com" {
+		t.Errorf("Time, Priority, Hostname = %s, %q, %q", e.Time, e.Priority, e.Hostname)
+	}
+	if e.Log == "" {
+		t.Errorf("document not used as log")
+	}
+	if _, ok := e.Fields["http.referer"]; ok {
+		t.Errorf("missing extra field kept")
+	}
+}
+
+func TestNewGenericDecoderErrors(t *testing.T) {
+	for name, params := range map[string]map[string]interface{}{
+		"unknown field":  {"fields": map[string]interface{}{"user": "$.user"}},
+		"invalid path":   {"fields": map[string]interface{}{"ip": "$.a["}},
+		"non-string map": {"extra": map[string]interface{}{"port": 22}},
+		"fields not map": {"fields": "ip"},
+	} {
+		if _, err := NewGenericDecoder(params); err == nil {
+			t.Errorf("%s: NewGenericDecoder accepted %v", name, params)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2024, 5, 1, 10, 22, 5, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2024-05-01T10:22:05Z", want},
+		{"2024-05-01T12:22:05+02:00", want},
+		{"1714558925", want},
+		{"1714558925.5", want.Add(500 * time.Millisecond)},
+		{"1714558925000", want},
+		{"1714558925000000", want},
+		{"1714558925000000000", want},
+		{"yesterday", time.Time{}},
+		{"", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseTime(tt.in); !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+)
+
+func init() {
+	Register("hubble", func(cfg Config) (Decoder, error) {
+		return NewHubbleDecoder(cfg.Params)
+	})
+}
+
+// HubbleEndpoint - кінцева точка потоку Cilium
+type HubbleEndpoint struct {
+	ID        uint32   `json:"ID"`
+	Identity  uint32   `json:"identity"`
+	Namespace string   `json:"namespace"`
+	Labels    []string `json:"labels"`
+	PodName   string   `json:"pod_name"`
+	Workloads []struct {
+		Name string `json:"name"`
+		Kind string `json:"kind"`
+	} `json:"workloads"`
+}
+
+// HubblePorts - порти транспортного рівня
+type HubblePorts struct {
+	SourcePort      uint32 `json:"source_port"`
+	DestinationPort uint32 `json:"destination_port"`
+}
+
+// HubbleFlow - потік Hubble (hubble observe -o json, Hubble exporter)
+type HubbleFlow struct {
+	Time    string `json:"time"`
+	Verdict string `json:"verdict"`
+	IP      struct {
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+		IPVersion   string `json:"ipVersion"`
+	} `json:"IP"`
+	L4 struct {
+		TCP  *HubblePorts `json:"TCP"`
+		UDP  *HubblePorts `json:"UDP"`
+		SCTP *HubblePorts `json:"SCTP"`
+		ICMP *struct {
+			Type uint32 `json:"type"`
+		} `json:"ICMPv4"`
+	} `json:"l4"`
+	Source           HubbleEndpoint `json:"source"`
+	Destination      HubbleEndpoint `json:"destination"`
+	Type             string         `json:"Type"`
+	NodeName         string         `json:"node_name"`
+	TrafficDirection string         `json:"traffic_direction"`
+	DropReasonDesc   string         `json:"drop_reason_desc"`
+	IsReply          bool           `json:"is_reply"`
+	Summary          string         `json:"Summary"`
+}
+
+// HubbleDecoder - декодер потоків Cilium Hubble
+type HubbleDecoder struct {
+	ipField      string
+	rulePrefix   string
+	verdicts     map[string]bool
+	externalOnly bool
+}
+
+// NewHubbleDecoder - створює новий HubbleDecoder
+func NewHubbleDecoder(params map[string]interface{}) (*HubbleDecoder, error) {
+	hd := &HubbleDecoder{}
+	var err error
+	if hd.ipField, err = stringParam(params, "ip_field", "source"); err != nil {
+		return nil, err
+	}
+	if hd.ipField != "source" && hd.ipField != "destination" {
+		return nil, fmt.Errorf("ip_field must be source or destination, got %q", hd.ipField)
+	}
+	if hd.rulePrefix, err = stringParam(params, "rule_prefix", "Hubble Flow"); err != nil {
+		return nil, err
+	}
+	if hd.externalOnly, err = boolParam(params, "external_only", false); err != nil {
+		return nil, err
+	}
+	verdicts, err := stringsParam(params, "verdicts")
+	if err != nil {
+		return nil, err
+	}
+	if len(verdicts) > 0 {
+		hd.verdicts = make(map[string]bool, len(verdicts))
+		for _, v := range verdicts {
+			hd.verdicts[strings.ToUpper(v)] = true
+		}
+	}
+	return hd, nil
+}
+
+// Decode - розбирає потік Hubble у подію
+func (hd *HubbleDecoder) Decode(data []byte) ([]actioner.Event, error) {
+	var envelope struct {
+		Flow     *HubbleFlow `json:"flow"`
+		NodeName string      `json:"node_name"`
+		Time     string      `json:"time"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid hubble flow: %v", err)
+	}
+
+	flow := envelope.Flow
+	if flow == nil {
+		// Експортер може надсилати потік без обгортки
+		var raw HubbleFlow
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("invalid hubble flow: %v", err)
+		}
+		if raw.Verdict == "" {
+			return nil, nil // Не потік (lost_events, node_status тощо)
+		}
+		flow = &raw
+	}
+	if flow.NodeName == "" {
+		flow.NodeName = envelope.NodeName
+	}
+	if flow.Time == "" {
+		flow.Time = envelope.Time
+	}
+
+	if hd.verdicts != nil && !hd.verdicts[flow.Verdict] {
+		return nil, nil
+	}
+
+	compact := new(bytes.Buffer)
+	if err := json.Compact(compact, data); err != nil {
+		compact.Reset()
+		compact.Write(data)
+	}
+	event := hd.Event(flow, compact.String())
+	if hd.externalOnly && !isExternalIP(event.IP) {
+		return nil, nil
+	}
+	return []actioner.Event{event}, nil
+}
+
+// Event - перетворює потік Hubble на подію
+func (hd *HubbleDecoder) Event(flow *HubbleFlow, raw string) actioner.Event {
+	event := actioner.Event{
+		RuleName: fmt.Sprintf("%s %s", hd.rulePrefix, flow.Verdict),
+		Log:      raw,
+		Priority: "Informational",
+		Hostname: flow.NodeName,
+		Fields: map[string]string{
+			"flow.verdict":           flow.Verdict,
+			"flow.type":              flow.Type,
+			"flow.traffic_direction": flow.TrafficDirection,
+			"flow.summary":           flow.Summary,
+			"flow.is_reply":          strconv.FormatBool(flow.IsReply),
+			"ip.source":              flow.IP.Source,
+			"ip.destination":         flow.IP.Destination,
+		},
+	}
+	if flow.Verdict == "DROPPED" {
+		event.Priority = "Warning"
+	}
+	if flow.DropReasonDesc != "" {
+		event.Fields["flow.drop_reason"] = flow.DropReasonDesc
+	}
+	if t, err := time.Parse(time.RFC3339Nano, flow.Time); err == nil {
+		event.Time = t
+	}
+
+	event.IP = flow.IP.Source
+	if hd.ipField == "destination" {
+		event.IP = flow.IP.Destination
+	}
+
+	var ports *HubblePorts
+	switch {
+	case flow.L4.TCP != nil:
+		event.Fields["l4.protocol"] = "TCP"
+		ports = flow.L4.TCP
+	case flow.L4.UDP != nil:
+		event.Fields["l4.protocol"] = "UDP"
+		ports = flow.L4.UDP
+	case flow.L4.SCTP != nil:
+		event.Fields["l4.protocol"] = "SCTP"
+		ports = flow.L4.SCTP
+	case flow.L4.ICMP != nil:
+		event.Fields["l4.protocol"] = "ICMP"
+	}
+	if ports != nil {
+		event.Fields["l4.source_port"] = strconv.FormatUint(uint64(ports.SourcePort), 10)
+		event.Fields["l4.destination_port"] = strconv.FormatUint(uint64(ports.DestinationPort), 10)
+	}
+
+	addEndpointFields(event.Fields, "source", flow.Source)
+	addEndpointFields(event.Fields, "destination", flow.Destination)
+
+	// Під, що належить кластеру: призначення для вхідного трафіку, джерело для вихідного
+	local := flow.Destination
+	if flow.TrafficDirection == "EGRESS" {
+		local = flow.Source
+	}
+	if local.PodName != "" {
+		event.Fields["k8s.pod.name"] = local.PodName
+		event.Fields["k8s.ns.name"] = local.Namespace
+	}
+	return event
+}
+
+// Name - повертає ім'я декодера
+func (hd *HubbleDecoder) Name() string { return "hubble" }
+
+// addEndpointFields - додає поля кінцевої точки потоку з префіксом
+func addEndpointFields(fields map[string]string, prefix string, ep HubbleEndpoint) {
+	if ep.PodName != "" {
+		fields[prefix+".pod_name"] = ep.PodName
+	}
+	if ep.Namespace != "" {
+		fields[prefix+".namespace"] = ep.Namespace
+	}
+	if ep.Identity != 0 {
+		fields[prefix+".identity"] = strconv.FormatUint(uint64(ep.Identity), 10)
+	}
+	if len(ep.Labels) > 0 {
+		fields[prefix+".labels"] = strings.Join(ep.Labels, ",")
+	}
+	if len(ep.Workloads) > 0 {
+		fields[prefix+".workload"] = ep.Workloads[0].Kind + "/" + ep.Workloads[0].Name
+	}
+}
+
+// isExternalIP - перевіряє, чи IP є публічною (не приватною, не loopback)
+func isExternalIP(s string) bool {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return false
+	}
+	return !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsUnspecified()
+}
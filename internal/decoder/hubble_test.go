@@ -0,0 +1,120 @@
+package decoder
+
+import "testing"
+
+func TestHubbleDecoder(t *testing.T) {
+	forwarded := []byte(`{"time":"2024-05-01T10:16:00Z","verdict":"FORWARDED","IP":{"source":"10.0.1.12","destination":"203.0.113.80"},` +
+		`"l4":{"UDP":{"source_port":53012,"destination_port":53}},"source":{"namespace":"web","pod_name":"frontend-7d9f"},` +
+		`"traffic_direction":"EGRESS","node_name":"kind-worker"}`)
+	tests := []struct {
+		name       string
+		params     map[string]interface{}
+		data       []byte
+		wantEvents int
+		wantIP     string
+		wantRule   string
+		wantFields map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "dropped ingress flow",
+			data:       readSample(t, "hubble_dropped.json"),
+			wantEvents: 1,
+			wantIP:     "198.51.100.23",
+			wantRule:   "Hubble Flow DROPPED",
+			wantFields: map[string]string{
+				"flow.drop_reason":       "POLICY_DENIED",
+				"l4.protocol":            "TCP",
+				"l4.destination_port":    "5432",
+				"source.labels":          "reserved:world",
+				"destination.workload":   "StatefulSet/postgres",
+				"k8s.pod.name":           "postgres-0", // Вхідний трафік - під призначення
+				"k8s.ns.name":            "payments",
+				"flow.traffic_direction": "INGRESS",
+				"destination.identity":   "51893",
+			},
+		},
+		{
+			name:       "ip_field destination",
+			params:     map[string]interface{}{"ip_field": "destination"},
+			data:       readSample(t, "hubble_dropped.json"),
+			wantEvents: 1,
+			wantIP:     "10.0.1.57",
+			wantRule:   "Hubble Flow DROPPED",
+		},
+		{
+			name:       "unwrapped egress flow",
+			params:     map[string]interface{}{"rule_prefix": "Cilium"},
+			data:       forwarded,
+			wantEvents: 1,
+			wantIP:     "10.0.1.12",
+			wantRule:   "Cilium FORWARDED",
+			wantFields: map[string]string{"l4.protocol": "UDP", "k8s.pod.name": "frontend-7d9f", "k8s.ns.name": "web"},
+		},
+		{
+			name:   "verdict filtered",
+			params: map[string]interface{}{"verdicts": []interface{}{"dropped"}},
+			data:   forwarded,
+		},
+		{
+			name:       "verdict kept",
+			params:     map[string]interface{}{"verdicts": []interface{}{"dropped"}},
+			data:       readSample(t, "hubble_dropped.json"),
+			wantEvents: 1,
+			wantIP:     "198.51.100.23",
+			wantRule:   "Hubble Flow DROPPED",
+		},
+		{
+			name:   "external_only skips private source",
+			params: map[string]interface{}{"external_only": true},
+			data:   forwarded,
+		},
+		{
+			name: "not a flow",
+			data: []byte(`{"lost_events":{"source":"HUBBLE_RING_BUFFER","num_events_lost":12}}`),
+		},
+		{
+			// Потік без IP-рівня (наприклад, ARP) дає подію без IP
+			name:       "missing ip",
+			data:       []byte(`{"verdict":"DROPPED","Type":"L3_L4","drop_reason_desc":"UNSUPPORTED_L3_PROTOCOL"}`),
+			wantEvents: 1,
+			wantRule:   "Hubble Flow DROPPED",
+			wantFields: map[string]string{"flow.drop_reason": "UNSUPPORTED_L3_PROTOCOL"},
+		},
+		{name: "malformed json", data: []byte(`{"flow":{"verdict":`), wantErr: true},
+		{name: "flow of wrong type", data: []byte(`{"flow":"DROPPED"}`), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hd, err := NewHubbleDecoder(tt.params)
+			if err != nil {
+				t.Fatalf("NewHubbleDecoder: %v", err)
+			}
+			events, err := hd.Decode(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(events) != tt.wantEvents {
+				t.Fatalf("got %d events, want %d", len(events), tt.wantEvents)
+			}
+			if tt.wantEvents == 0 {
+				return
+			}
+			e := events[0]
+			if e.IP != tt.wantIP || e.RuleName != tt.wantRule {
+				t.Errorf("IP, RuleName = %q, %q; want %q, %q", e.IP, e.RuleName, tt.wantIP, tt.wantRule)
+			}
+			for k, want := range tt.wantFields {
+				if got := e.Field(k); got != want {
+					t.Errorf("Field(%q) = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewHubbleDecoderInvalidIPField(t *testing.T) {
+	if _, err := NewHubbleDecoder(map[string]interface{}{"ip_field": "pod"}); err == nil {
+		t.Errorf("ip_field pod accepted")
+	}
+}
@@ -0,0 +1,180 @@
+package decoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+)
+
+func init() {
+	Register("k8s_audit", func(cfg Config) (Decoder, error) {
+		return NewK8sAuditDecoder(cfg.Params)
+	})
+}
+
+// DefaultK8sAuditRule - шаблон імені правила для подій аудиту
+const DefaultK8sAuditRule = "K8s API {code} {verb} {resource}"
+
+// K8sAuditEvent - подія аудиту Kubernetes (audit.k8s.io/v1 Event)
+type K8sAuditEvent struct {
+	Level      string `json:"level"`
+	AuditID    string `json:"auditID"`
+	Stage      string `json:"stage"`
+	RequestURI string `json:"requestURI"`
+	Verb       string `json:"verb"`
+	User       struct {
+		Username string   `json:"username"`
+		Groups   []string `json:"groups"`
+	} `json:"user"`
+	SourceIPs []string `json:"sourceIPs"`
+	UserAgent string   `json:"userAgent"`
+	ObjectRef *struct {
+		Resource    string `json:"resource"`
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+		APIGroup    string `json:"apiGroup"`
+		Subresource string `json:"subresource"`
+	} `json:"objectRef"`
+	ResponseStatus *struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+		Code   int    `json:"code"`
+	} `json:"responseStatus"`
+	RequestReceivedTimestamp string `json:"requestReceivedTimestamp"`
+	StageTimestamp           string `json:"stageTimestamp"`
+}
+
+// K8sAuditDecoder - декодер аудит-вебхука Kubernetes (EventList або окремий Event)
+type K8sAuditDecoder struct {
+	ruleFormat string
+	stages     map[string]bool
+}
+
+// NewK8sAuditDecoder - створює новий K8sAuditDecoder
+func NewK8sAuditDecoder(params map[string]interface{}) (*K8sAuditDecoder, error) {
+	kd := &K8sAuditDecoder{}
+	var err error
+	if kd.ruleFormat, err = stringParam(params, "rule_format", DefaultK8sAuditRule); err != nil {
+		return nil, err
+	}
+	stages, err := stringsParam(params, "stages")
+	if err != nil {
+		return nil, err
+	}
+	if len(stages) == 0 {
+		stages = []string{"ResponseComplete", "Panic"}
+	}
+	kd.stages = make(map[string]bool, len(stages))
+	for _, s := range stages {
+		kd.stages[s] = true
+	}
+	return kd, nil
+}
+
+// Decode - розбирає пакет EventList або окрему подію аудиту
+func (kd *K8sAuditDecoder) Decode(data []byte) ([]actioner.Event, error) {
+	var head struct {
+		Kind       string          `json:"kind"`
+		APIVersion string          `json:"apiVersion"`
+		Items      json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("invalid audit event: %v", err)
+	}
+
+	var items []K8sAuditEvent
+	switch head.Kind {
+	case "EventList":
+		if err := json.Unmarshal(head.Items, &items); err != nil {
+			return nil, fmt.Errorf("invalid audit EventList: %v", err)
+		}
+	case "Event":
+		var ev K8sAuditEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return nil, fmt.Errorf("invalid audit event: %v", err)
+		}
+		items = []K8sAuditEvent{ev}
+	default:
+		return nil, fmt.Errorf("unsupported audit kind %q (expected EventList or Event)", head.Kind)
+	}
+
+	events := make([]actioner.Event, 0, len(items))
+	for i := range items {
+		if !kd.stages[items[i].Stage] {
+			continue
+		}
+		events = append(events, kd.Event(&items[i]))
+	}
+	return events, nil
+}
+
+// Event - перетворює подію аудиту на подію; поля названо як у джерелі k8s_audit Falco
+func (kd *K8sAuditDecoder) Event(ae *K8sAuditEvent) actioner.Event {
+	event := actioner.Event{
+		Priority: "Informational",
+		Fields: map[string]string{
+			"ka.auditid":     ae.AuditID,
+			"ka.stage":       ae.Stage,
+			"ka.verb":        ae.Verb,
+			"ka.uri":         ae.RequestURI,
+			"ka.user.name":   ae.User.Username,
+			"ka.user.groups": strings.Join(ae.User.Groups, ","),
+			"ka.useragent":   ae.UserAgent,
+			"ka.sourceips":   strings.Join(ae.SourceIPs, ","),
+		},
+	}
+	if len(ae.SourceIPs) > 0 {
+		event.IP = ae.SourceIPs[0]
+	}
+	ts := ae.StageTimestamp
+	if ts == "" {
+		ts = ae.RequestReceivedTimestamp
+	}
+	if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+		event.Time = t
+	}
+
+	var resource, namespace, name, subresource string
+	if ref := ae.ObjectRef; ref != nil {
+		resource, namespace, name, subresource = ref.Resource, ref.Namespace, ref.Name, ref.Subresource
+		event.Fields["ka.target.resource"] = ref.Resource
+		event.Fields["ka.target.namespace"] = ref.Namespace
+		event.Fields["ka.target.name"] = ref.Name
+		event.Fields["ka.target.subresource"] = ref.Subresource
+		event.Fields["ka.target.apigroup"] = ref.APIGroup
+	}
+	var code int
+	var reason string
+	if rs := ae.ResponseStatus; rs != nil {
+		code, reason = rs.Code, rs.Reason
+		event.Fields["ka.response.code"] = strconv.Itoa(rs.Code)
+		event.Fields["ka.response.reason"] = rs.Reason
+	}
+	switch {
+	case code >= 500:
+		event.Priority = "Error"
+	case code >= 400:
+		event.Priority = "Warning"
+	}
+
+	event.RuleName = strings.NewReplacer(
+		"{code}", strconv.Itoa(code),
+		"{verb}", ae.Verb,
+		"{resource}", resource,
+		"{subresource}", subresource,
+		"{namespace}", namespace,
+		"{name}", name,
+		"{user}", ae.User.Username,
+		"{reason}", reason,
+	).Replace(kd.ruleFormat)
+	event.Log = fmt.Sprintf("%s %s by %s from %s: %d %s", ae.Verb, ae.RequestURI, ae.User.Username,
+		strings.Join(ae.SourceIPs, ","), code, reason)
+	return event
+}
+
+// Name - повертає ім'я декодера
+func (kd *K8sAuditDecoder) Name() string { return "k8s_audit" }
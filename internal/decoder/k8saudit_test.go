@@ -0,0 +1,120 @@
+package decoder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestK8sAuditDecoder(t *testing.T) {
+	type want struct {
+		ip     string
+		rule   string
+		fields map[string]string
+	}
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		data    []byte
+		want    []want
+		wantErr bool
+	}{
+		{
+			// RequestReceived за замовчуванням не обробляється - відповідь ще невідома
+			name: "event list with default stages",
+			data: readSample(t, "k8saudit_eventlist.json"),
+			want: []want{
+				{
+					ip:   "10.0.1.12", // Перша з sourceIPs
+					rule: "K8s API 403 create pods",
+					fields: map[string]string{
+						"ka.user.name":          "system:serviceaccount:web:frontend",
+						"ka.target.subresource": "exec",
+						"ka.target.namespace":   "payments",
+						"ka.response.reason":    "Forbidden",
+						"ka.sourceips":          "10.0.1.12,192.168.49.1",
+					},
+				},
+				{
+					ip:   "203.0.113.90",
+					rule: "K8s API 201 create clusterrolebindings",
+					fields: map[string]string{
+						"ka.target.name":     "backdoor-admin",
+						"ka.target.apigroup": "rbac.authorization.k8s.io",
+						"ka.user.groups":     "system:masters,system:authenticated",
+					},
+				},
+			},
+		},
+		{
+			name:   "configured stages and rule format",
+			params: map[string]interface{}{"stages": []interface{}{"RequestReceived"}, "rule_format": "{verb} {resource}/{subresource} by {user}"},
+			data:   readSample(t, "k8saudit_eventlist.json"),
+			want:   []want{{ip: "10.0.1.12", rule: "create pods/exec by system:serviceaccount:web:frontend"}},
+		},
+		{
+			name: "single event",
+			data: []byte(`{"kind":"Event","apiVersion":"audit.k8s.io/v1","stage":"ResponseComplete","verb":"delete","sourceIPs":["10.0.0.9"],` +
+				`"objectRef":{"resource":"secrets","namespace":"kube-system","name":"token"},"responseStatus":{"code":200}}`),
+			want: []want{{ip: "10.0.0.9", rule: "K8s API 200 delete secrets"}},
+		},
+		{
+			// Подія без sourceIPs (наприклад, від самого API-сервера) - без IP
+			name: "missing ip",
+			data: []byte(`{"kind":"Event","stage":"ResponseComplete","verb":"get","requestURI":"/healthz","responseStatus":{"code":200}}`),
+			want: []want{{rule: "K8s API 200 get "}},
+		},
+		{name: "empty event list", data: []byte(`{"kind":"EventList","items":[]}`)},
+		{name: "malformed json", data: []byte(`{"kind":"EventList","items":[`), wantErr: true},
+		{name: "items not a list", data: []byte(`{"kind":"EventList","items":{"stage":"Panic"}}`), wantErr: true},
+		{name: "unsupported kind", data: []byte(`{"kind":"Policy","apiVersion":"audit.k8s.io/v1"}`), wantErr: true},
+		{name: "missing kind", data: []byte(`{"stage":"ResponseComplete","verb":"get"}`), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kd, err := NewK8sAuditDecoder(tt.params)
+			if err != nil {
+				t.Fatalf("NewK8sAuditDecoder: %v", err)
+			}
+			events, err := kd.Decode(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(events), len(tt.want))
+			}
+			for i, w := range tt.want {
+				e := events[i]
+				if e.IP != w.ip || e.RuleName != w.rule {
+					t.Errorf("event %d: IP, RuleName = %q, %q; want %q, %q", i, e.IP, e.RuleName, w.ip, w.rule)
+				}
+				for k, want := range w.fields {
+					if got := e.Field(k); got != want {
+						t.Errorf("event %d: Field(%q) = %q, want %q", i, k, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestK8sAuditDecoderPriorityAndTime(t *testing.T) {
+	kd, err := NewK8sAuditDecoder(nil)
+	if err != nil {
+		t.Fatalf("NewK8sAuditDecoder: %v", err)
+	}
+	events, err := kd.Decode(readSample(t, "k8saudit_eventlist.json"))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if events[0].Priority != "Warning" || events[1].Priority != "Informational" {
+		t.Errorf("priorities = %q, %q; want Warning, Informational", events[0].Priority, events[1].Priority)
+	}
+	if want := time.Date(2024, 5, 1, 10, 35, 20, 106342000, time.UTC); !events[0].Time.Equal(want) {
+		t.Errorf("Time = %s, want stageTimestamp %s", events[0].Time, want)
+	}
+
+	events, err = kd.Decode([]byte(`{"kind":"Event","stage":"Panic","verb":"list","responseStatus":{"code":500}}`))
+	if err != nil || len(events) != 1 || events[0].Priority != "Error" {
+		t.Errorf("panic event = %+v, %v; want one event with priority Error", events, err)
+	}
+}
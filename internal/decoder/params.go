@@ -0,0 +1,123 @@
+package decoder
+
+import (
+	"fmt"
+	"time"
+)
+
+// stringParam - повертає рядковий параметр або значення за замовчуванням
+func stringParam(params map[string]interface{}, key, def string) (string, error) {
+	v, ok := params[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string, got %T", key, v)
+	}
+	return s, nil
+}
+
+// stringsParam - повертає список рядків (YAML-список або один рядок)
+func stringsParam(params map[string]interface{}, key string) ([]string, error) {
+	switch v := params[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of strings, got %T item", key, item)
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("%s must be a list of strings, got %T", key, v)
+	}
+}
+
+// boolParam - повертає логічний параметр або значення за замовчуванням
+func boolParam(params map[string]interface{}, key string, def bool) (bool, error) {
+	switch v := params[key].(type) {
+	case nil:
+		return def, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("%s must be a boolean, got %T", key, v)
+	}
+}
+
+// intParam - повертає числовий параметр або значення за замовчуванням
+func intParam(params map[string]interface{}, key string, def int) (int, error) {
+	switch v := params[key].(type) {
+	case nil:
+		return def, nil
+	case int:
+		return v, nil
+	case float64:
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("%s must be a number, got %T", key, v)
+	}
+}
+
+// durationParam - повертає тривалість ("30s", "5m") або значення за замовчуванням
+func durationParam(params map[string]interface{}, key string, def time.Duration) (time.Duration, error) {
+	switch v := params[key].(type) {
+	case nil:
+		return def, nil
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s format: %v", key, err)
+		}
+		return d, nil
+	case int:
+		return time.Duration(v) * time.Second, nil
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	default:
+		return 0, fmt.Errorf("%s must be a duration, got %T", key, v)
+	}
+}
+
+// stringMapParam - повертає словник рядків (YAML-об'єкт)
+func stringMapParam(params map[string]interface{}, key string) (map[string]string, error) {
+	switch v := params[key].(type) {
+	case nil:
+		return nil, nil
+	case map[string]string:
+		return v, nil
+	case map[string]interface{}:
+		out := make(map[string]string, len(v))
+		for k, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s.%s must be a string, got %T", key, k, item)
+			}
+			out[k] = s
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("%s must be a map of strings, got %T", key, v)
+	}
+}
+
+// mapParam - повертає вкладений об'єкт параметрів
+func mapParam(params map[string]interface{}, key string) (map[string]interface{}, error) {
+	switch v := params[key].(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s must be a map, got %T", key, v)
+	}
+}
@@ -0,0 +1,127 @@
+package decoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment - крок шляху: ключ об'єкта або індекс масиву
+type pathSegment struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+// jsonPath - спрощений JSONPath ("$.a.b[0]['c.d']" або "a.b.0")
+type jsonPath struct {
+	expr     string
+	segments []pathSegment
+}
+
+// compilePath - розбирає вираз шляху
+func compilePath(expr string) (jsonPath, error) {
+	p := jsonPath{expr: expr}
+	rest := strings.TrimSpace(expr)
+	rest = strings.TrimPrefix(rest, "$")
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return p, fmt.Errorf("invalid path %q: unclosed bracket", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				p.segments = append(p.segments, pathSegment{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			idx, err := strconv.Atoi(inner)
+			if err != nil {
+				return p, fmt.Errorf("invalid path %q: bad index %q", expr, inner)
+			}
+			p.segments = append(p.segments, pathSegment{index: idx, isIdx: true})
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			rest = rest[end:]
+			if idx, err := strconv.Atoi(key); err == nil {
+				p.segments = append(p.segments, pathSegment{key: key, index: idx, isIdx: true})
+				continue
+			}
+			p.segments = append(p.segments, pathSegment{key: key})
+		}
+	}
+	return p, nil
+}
+
+// lookup - повертає значення за шляхом у розібраному JSON-документі
+func (p jsonPath) lookup(doc interface{}) (interface{}, bool) {
+	if p.expr == "" {
+		return nil, false // Шлях не налаштовано
+	}
+	cur := doc
+	for _, seg := range p.segments {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[seg.key]
+			if !ok {
+				return nil, false
+			}
+			cur = v
+		case []interface{}:
+			if !seg.isIdx {
+				return nil, false
+			}
+			idx := seg.index
+			if idx < 0 {
+				idx += len(node)
+			}
+			if idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			cur = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return cur, cur != nil
+}
+
+// lookupString - повертає значення за шляхом у вигляді рядка
+func (p jsonPath) lookupString(doc interface{}) string {
+	v, ok := p.lookup(doc)
+	if !ok {
+		return ""
+	}
+	return valueString(v)
+}
+
+// valueString - перетворює JSON-значення на рядок (об'єкти й масиви - у компактний JSON)
+func valueString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(data)
+	}
+}
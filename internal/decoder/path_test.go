@@ -0,0 +1,96 @@
+package decoder
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`{
+		"a": {"b": "plain", "n": 42.5, "ok": true, "none": null, "obj": {"y": [1, "z"]}},
+		"arr": [{"ip": "10.0.0.1"}, {"ip": "10.0.0.2"}],
+		"c.d": "dotted",
+		"m": {"0": "zero key"},
+		"list": []
+	}`))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatalf("decode fixture: %v", err)
+	}
+
+	tests := []struct {
+		expr   string
+		want   string
+		wantOK bool
+	}{
+		{"$.a.b", "plain", true},
+		{"a.b", "plain", true},
+		{" $.a.b ", "plain", true},
+		{"$.a.n", "42.5", true},
+		{"$.a.ok", "true", true},
+		{"$.a.obj", `{"y":[1,"z"]}`, true},
+		{"$.a.obj.y[1]", "z", true},
+		{"$.arr[0].ip", "10.0.0.1", true},
+		{"arr.1.ip", "10.0.0.2", true},
+		{"$.arr[-1].ip", "10.0.0.2", true},
+		{"$['c.d']", "dotted", true},
+		{`$["c.d"]`, "dotted", true},
+		{"$.m.0", "zero key", true},
+		{"$", "", true}, // Корінь документа - об'єкт, перевіряється лише ok
+		{"$.a.none", "", false},
+		{"$.a.missing", "", false},
+		{"$.a.b.c", "", false},     // Крок усередину рядка
+		{"$.arr[2].ip", "", false}, // Індекс поза масивом
+		{"$.arr[-3]", "", false},
+		{"$.arr.ip", "", false}, // Ключ замість індексу в масиві
+		{"$.m[0]", "", false},   // Індекс у дужках не є ключем об'єкта
+		{"$.list[0]", "", false},
+		{"$.a..b", "plain", true}, // Порожні кроки ігноруються
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := compilePath(tt.expr)
+			if err != nil {
+				t.Fatalf("compilePath(%q): %v", tt.expr, err)
+			}
+			v, ok := p.lookup(doc)
+			if ok != tt.wantOK {
+				t.Fatalf("lookup ok = %v, want %v (value %v)", ok, tt.wantOK, v)
+			}
+			if tt.expr == "$" {
+				return
+			}
+			if got := p.lookupString(doc); got != tt.want {
+				t.Errorf("lookupString = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"$.a[",
+		"$.arr[0",
+		"$.arr[x]",
+		"$.arr[]",
+		"$['c.d]",
+		`$["c.d']`,
+		"$.arr[1.5]",
+	} {
+		if _, err := compilePath(expr); err == nil {
+			t.Errorf("compilePath(%q) accepted a malformed path", expr)
+		}
+	}
+}
+
+func TestJSONPathUnconfigured(t *testing.T) {
+	var p jsonPath
+	if v, ok := p.lookup(map[string]interface{}{"a": "b"}); ok {
+		t.Errorf("empty path returned %v", v)
+	}
+	if got := p.lookupString(nil); got != "" {
+		t.Errorf("empty path lookupString = %q", got)
+	}
+}
@@ -0,0 +1,221 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+)
+
+func init() {
+	Register("scc", func(cfg Config) (Decoder, error) {
+		return NewSCCDecoder(cfg.Params)
+	})
+}
+
+// SCCConnection - мережеве з'єднання, пов'язане з висновком
+type SCCConnection struct {
+	DestinationIP   string `json:"destinationIp"`
+	DestinationPort int    `json:"destinationPort"`
+	SourceIP        string `json:"sourceIp"`
+	SourcePort      int    `json:"sourcePort"`
+	Protocol        string `json:"protocol"`
+}
+
+// SCCFinding - висновок (finding) Security Command Center
+type SCCFinding struct {
+	Name             string                 `json:"name"`
+	ResourceName     string                 `json:"resourceName"`
+	State            string                 `json:"state"`
+	Category         string                 `json:"category"`
+	Severity         string                 `json:"severity"`
+	FindingClass     string                 `json:"findingClass"`
+	EventTime        string                 `json:"eventTime"`
+	ExternalURI      string                 `json:"externalUri"`
+	SourceProperties map[string]interface{} `json:"sourceProperties"`
+	Connections      []SCCConnection        `json:"connections"`
+	Indicator        *struct {
+		IPAddresses []string `json:"ipAddresses"`
+		Domains     []string `json:"domains"`
+	} `json:"indicator"`
+	MitreAttack *struct {
+		PrimaryTactic     string   `json:"primaryTactic"`
+		PrimaryTechniques []string `json:"primaryTechniques"`
+	} `json:"mitreAttack"`
+}
+
+// SCCNotification - повідомлення NotificationConfig Security Command Center
+type SCCNotification struct {
+	NotificationConfigName string      `json:"notificationConfigName"`
+	Finding                *SCCFinding `json:"finding"`
+	Resource               *struct {
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+		ProjectName string `json:"projectName"`
+		Type        string `json:"type"`
+	} `json:"resource"`
+}
+
+// SCCDecoder - декодер сповіщень про висновки Security Command Center
+type SCCDecoder struct {
+	ipFrom     []string
+	activeOnly bool
+}
+
+// NewSCCDecoder - створює новий SCCDecoder
+func NewSCCDecoder(params map[string]interface{}) (*SCCDecoder, error) {
+	sd := &SCCDecoder{}
+	var err error
+	if sd.ipFrom, err = stringsParam(params, "ip_from"); err != nil {
+		return nil, err
+	}
+	if len(sd.ipFrom) == 0 {
+		sd.ipFrom = []string{"source"}
+	}
+	for _, from := range sd.ipFrom {
+		if from != "source" && from != "destination" && from != "indicator" {
+			return nil, fmt.Errorf("ip_from must contain source, destination or indicator, got %q", from)
+		}
+	}
+	if sd.activeOnly, err = boolParam(params, "active_only", true); err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+// Decode - розбирає сповіщення SCC (або окремий висновок) у події, по одній на кожну IP
+func (sd *SCCDecoder) Decode(data []byte) ([]actioner.Event, error) {
+	var n SCCNotification
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, fmt.Errorf("invalid SCC notification: %v", err)
+	}
+	if n.Finding == nil {
+		var f SCCFinding
+		if err := json.Unmarshal(data, &f); err != nil || f.Category == "" {
+			return nil, fmt.Errorf("invalid SCC notification: no finding")
+		}
+		n.Finding = &f
+	}
+	f := n.Finding
+	if sd.activeOnly && f.State != "" && f.State != "ACTIVE" {
+		return nil, nil
+	}
+
+	base := actioner.Event{
+		RuleName: f.Category,
+		Priority: sccSeverityPriority(f.Severity),
+		Fields: map[string]string{
+			"finding.name":          f.Name,
+			"finding.category":      f.Category,
+			"finding.severity":      f.Severity,
+			"finding.state":         f.State,
+			"finding.class":         f.FindingClass,
+			"finding.resource_name": f.ResourceName,
+		},
+	}
+	if t, err := time.Parse(time.RFC3339Nano, f.EventTime); err == nil {
+		base.Time = t
+	}
+	if f.ExternalURI != "" {
+		base.Fields["finding.external_uri"] = f.ExternalURI
+	}
+	if n.Resource != nil {
+		base.Hostname = n.Resource.DisplayName
+		base.Fields["resource.type"] = n.Resource.Type
+		base.Fields["resource.project"] = n.Resource.ProjectName
+	}
+	if f.FindingClass != "" {
+		base.Tags = append(base.Tags, strings.ToLower(f.FindingClass))
+	}
+	if f.MitreAttack != nil && f.MitreAttack.PrimaryTactic != "" {
+		base.Tags = append(base.Tags, "mitre_"+strings.ToLower(f.MitreAttack.PrimaryTactic))
+		base.Fields["mitre.tactic"] = f.MitreAttack.PrimaryTactic
+		base.Fields["mitre.techniques"] = strings.Join(f.MitreAttack.PrimaryTechniques, ",")
+	}
+	for k, v := range f.SourceProperties {
+		base.Fields["finding.source_properties."+k] = valueString(v)
+	}
+	if len(f.Connections) > 0 {
+		c := f.Connections[0]
+		base.Fields["connection.source_ip"] = c.SourceIP
+		base.Fields["connection.source_port"] = strconv.Itoa(c.SourcePort)
+		base.Fields["connection.destination_ip"] = c.DestinationIP
+		base.Fields["connection.destination_port"] = strconv.Itoa(c.DestinationPort)
+		base.Fields["connection.protocol"] = c.Protocol
+	}
+
+	compact := new(bytes.Buffer)
+	if err := json.Compact(compact, data); err == nil {
+		base.Log = compact.String()
+	}
+
+	ips := sd.ips(f)
+	if len(ips) == 0 {
+		return []actioner.Event{base}, nil
+	}
+	events := make([]actioner.Event, 0, len(ips))
+	for _, ip := range ips {
+		event := base
+		event.IP = ip
+		event.Fields = make(map[string]string, len(base.Fields))
+		for k, v := range base.Fields {
+			event.Fields[k] = v
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// ips - повертає унікальні IP висновку у порядку, заданому ip_from
+func (sd *SCCDecoder) ips(f *SCCFinding) []string {
+	seen := make(map[string]bool)
+	var ips []string
+	add := func(ip string) {
+		if ip != "" && !seen[ip] {
+			seen[ip] = true
+			ips = append(ips, ip)
+		}
+	}
+	for _, from := range sd.ipFrom {
+		switch from {
+		case "source":
+			for _, c := range f.Connections {
+				add(c.SourceIP)
+			}
+		case "destination":
+			for _, c := range f.Connections {
+				add(c.DestinationIP)
+			}
+		case "indicator":
+			if f.Indicator != nil {
+				for _, ip := range f.Indicator.IPAddresses {
+					add(ip)
+				}
+			}
+		}
+	}
+	return ips
+}
+
+// Name - повертає ім'я декодера
+func (sd *SCCDecoder) Name() string { return "scc" }
+
+// sccSeverityPriority - перетворює severity SCC на пріоритет у стилі Falco
+func sccSeverityPriority(severity string) string {
+	switch severity {
+	case "CRITICAL":
+		return "Critical"
+	case "HIGH":
+		return "Error"
+	case "MEDIUM":
+		return "Warning"
+	case "LOW":
+		return "Notice"
+	default:
+		return "Informational"
+	}
+}
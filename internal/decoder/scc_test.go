@@ -0,0 +1,117 @@
+package decoder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSCCDecoder(t *testing.T) {
+	sample := readSample(t, "scc_notification.json")
+	inactive := []byte(strings.Replace(string(sample), `"state": "ACTIVE"`, `"state": "INACTIVE"`, 1))
+	const rule = "Malware: Cryptomining Bad IP"
+	tests := []struct {
+		name       string
+		params     map[string]interface{}
+		data       []byte
+		wantIPs    []string
+		wantRule   string
+		wantFields map[string]string
+		wantErr    bool
+	}{
+		{
+			name:     "source ip by default",
+			data:     sample,
+			wantIPs:  []string{"10.132.0.14"},
+			wantRule: rule,
+			wantFields: map[string]string{
+				"finding.severity":                            "HIGH",
+				"connection.destination_port":                 "3333",
+				"resource.type":                               "google.compute.Instance",
+				"mitre.tactic":                                "IMPACT",
+				"finding.source_properties.detectionPriority": "HIGH",
+			},
+		},
+		{
+			// Індикатор і призначення - та сама IP, подія одна
+			name:     "several ip sources deduplicated",
+			params:   map[string]interface{}{"ip_from": []interface{}{"indicator", "destination", "source"}},
+			data:     sample,
+			wantIPs:  []string{"198.51.100.77", "10.132.0.14"},
+			wantRule: rule,
+		},
+		{
+			name:     "bare finding",
+			params:   map[string]interface{}{"ip_from": []interface{}{"indicator"}},
+			data:     []byte(`{"category":"Persistence: IAM Anomalous Grant","state":"ACTIVE","severity":"LOW","indicator":{"ipAddresses":["203.0.113.40"]}}`),
+			wantIPs:  []string{"203.0.113.40"},
+			wantRule: "Persistence: IAM Anomalous Grant",
+		},
+		{
+			// Висновок без IP - одна подія без IP
+			name:       "missing ip",
+			data:       []byte(`{"finding":{"category":"Open Firewall","state":"ACTIVE","severity":"MEDIUM","findingClass":"MISCONFIGURATION"}}`),
+			wantIPs:    []string{""},
+			wantRule:   "Open Firewall",
+			wantFields: map[string]string{"finding.class": "MISCONFIGURATION"},
+		},
+		{name: "inactive finding filtered", data: inactive},
+		{
+			name:     "inactive finding kept",
+			params:   map[string]interface{}{"active_only": false},
+			data:     inactive,
+			wantIPs:  []string{"10.132.0.14"},
+			wantRule: rule,
+		},
+		{name: "malformed json", data: []byte(`{"finding":`), wantErr: true},
+		{name: "no finding", data: []byte(`{"notificationConfigName":"organizations/1/notificationConfigs/x"}`), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sd, err := NewSCCDecoder(tt.params)
+			if err != nil {
+				t.Fatalf("NewSCCDecoder: %v", err)
+			}
+			events, err := sd.Decode(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(events) != len(tt.wantIPs) {
+				t.Fatalf("got %d events, want %d", len(events), len(tt.wantIPs))
+			}
+			for i, e := range events {
+				if e.IP != tt.wantIPs[i] || e.RuleName != tt.wantRule {
+					t.Errorf("event %d: IP, RuleName = %q, %q; want %q, %q", i, e.IP, e.RuleName, tt.wantIPs[i], tt.wantRule)
+				}
+				for k, want := range tt.wantFields {
+					if got := e.Field(k); got != want {
+						t.Errorf("event %d: Field(%q) = %q, want %q", i, k, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSCCDecoderEventsDoNotShareFields(t *testing.T) {
+	sd, err := NewSCCDecoder(map[string]interface{}{"ip_from": []interface{}{"source", "destination"}})
+	if err != nil {
+		t.Fatalf("NewSCCDecoder: %v", err)
+	}
+	events, err := sd.Decode(readSample(t, "scc_notification.json"))
+	if err != nil || len(events) != 2 {
+		t.Fatalf("Decode = %d events, %v; want 2", len(events), err)
+	}
+	events[0].Fields["scenario.name"] = "block"
+	if events[1].Field("scenario.name") != "" {
+		t.Errorf("events of one finding share the fields map")
+	}
+	if events[0].Priority != "Error" || events[0].Hostname != "web-frontend-3" {
+		t.Errorf("Priority, Hostname = %q, %q", events[0].Priority, events[0].Hostname)
+	}
+}
+
+func TestNewSCCDecoderInvalidIPFrom(t *testing.T) {
+	if _, err := NewSCCDecoder(map[string]interface{}{"ip_from": []interface{}{"resource"}}); err == nil {
+		t.Errorf("ip_from resource accepted")
+	}
+}
@@ -0,0 +1,221 @@
+package decoder
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+)
+
+func init() {
+	Register("syslog", func(cfg Config) (Decoder, error) {
+		return NewSyslogDecoder(cfg.Params)
+	})
+}
+
+var (
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD [MSG]
+	rfc5424Re = regexp.MustCompile(`^<(\d{1,3})>(\d{1,2}) (\S+) (\S+) (\S+) (\S+) (\S+) (-|(?:\[(?:[^\]\\]|\\.)*\])+)(?: (.*))?$`)
+	// <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+	rfc3164Re = regexp.MustCompile(`^<(\d{1,3})>([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (\S+) ([^:\[\s]+)(?:\[(\d+)\])?: ?(.*)$`)
+)
+
+// syslogSeverities - рівні severity syslog; збігаються з пріоритетами Falco
+var syslogSeverities = []string{"Emergency", "Alert", "Critical", "Error", "Warning", "Notice", "Informational", "Debug"}
+
+// SyslogMessage - розібране повідомлення syslog
+type SyslogMessage struct {
+	Facility int
+	Severity int
+	Time     time.Time
+	Hostname string
+	AppName  string
+	ProcID   string
+	MsgID    string
+	Message  string
+}
+
+// ParseSyslog - розбирає повідомлення у форматі RFC 5424 або RFC 3164
+func ParseSyslog(line string) (*SyslogMessage, error) {
+	line = strings.TrimRight(line, "\r\n\x00")
+	if m := rfc5424Re.FindStringSubmatch(line); m != nil {
+		msg := &SyslogMessage{
+			Hostname: nilValue(m[4]),
+			AppName:  nilValue(m[5]),
+			ProcID:   nilValue(m[6]),
+			MsgID:    nilValue(m[7]),
+			Message:  strings.TrimPrefix(m[9], "\ufeff"),
+		}
+		if err := msg.setPriority(m[1]); err != nil {
+			return nil, err
+		}
+		if t, err := time.Parse(time.RFC3339Nano, m[3]); err == nil {
+			msg.Time = t
+		}
+		return msg, nil
+	}
+	if m := rfc3164Re.FindStringSubmatch(line); m != nil {
+		msg := &SyslogMessage{
+			Hostname: m[3],
+			AppName:  m[4],
+			ProcID:   m[5],
+			Message:  m[6],
+		}
+		if err := msg.setPriority(m[1]); err != nil {
+			return nil, err
+		}
+		// У RFC 3164 немає року - беремо поточний
+		if t, err := time.ParseInLocation("Jan _2 15:04:05", m[2], time.Local); err == nil {
+			now := time.Now()
+			msg.Time = t.AddDate(now.Year(), 0, 0)
+			if msg.Time.After(now.Add(24 * time.Hour)) {
+				msg.Time = msg.Time.AddDate(-1, 0, 0)
+			}
+		}
+		return msg, nil
+	}
+	return nil, fmt.Errorf("not an RFC 5424 or RFC 3164 syslog message")
+}
+
+// setPriority - заповнює facility і severity з PRI
+func (m *SyslogMessage) setPriority(pri string) error {
+	n, err := strconv.Atoi(pri)
+	if err != nil || n > 191 {
+		return fmt.Errorf("invalid syslog priority %q", pri)
+	}
+	m.Facility, m.Severity = n/8, n%8
+	return nil
+}
+
+// nilValue - замінює NILVALUE ("-") RFC 5424 на порожній рядок
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// syslogExtractor - регулярний вираз з іменованими групами (ip, rule та довільні поля)
+type syslogExtractor struct {
+	app      string
+	pattern  *regexp.Regexp
+	rule     string
+	priority string
+}
+
+// SyslogDecoder - декодер повідомлень syslog з регулярними екстракторами
+type SyslogDecoder struct {
+	extractors    []syslogExtractor
+	emitUnmatched bool
+}
+
+// NewSyslogDecoder - створює новий SyslogDecoder
+func NewSyslogDecoder(params map[string]interface{}) (*SyslogDecoder, error) {
+	sd := &SyslogDecoder{}
+	var err error
+	if sd.emitUnmatched, err = boolParam(params, "emit_unmatched", false); err != nil {
+		return nil, err
+	}
+
+	raw, ok := params["extractors"].([]interface{})
+	if !ok && !sd.emitUnmatched {
+		return nil, fmt.Errorf("syslog decoder needs a list of extractors")
+	}
+	for i, item := range raw {
+		p, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("extractors[%d] must be a map, got %T", i, item)
+		}
+		var ex syslogExtractor
+		pattern, err := stringParam(p, "pattern", "")
+		if err != nil {
+			return nil, err
+		}
+		if ex.pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid extractors[%d].pattern: %v", i, err)
+		}
+		if ex.app, err = stringParam(p, "app", ""); err != nil {
+			return nil, err
+		}
+		if ex.rule, err = stringParam(p, "rule", ""); err != nil {
+			return nil, err
+		}
+		if ex.priority, err = stringParam(p, "priority", ""); err != nil {
+			return nil, err
+		}
+		if ex.rule == "" && ex.pattern.SubexpIndex("rule") < 0 {
+			return nil, fmt.Errorf("extractors[%d] needs a rule or a (?P<rule>...) group", i)
+		}
+		sd.extractors = append(sd.extractors, ex)
+	}
+	return sd, nil
+}
+
+// Decode - розбирає повідомлення syslog і застосовує перший відповідний екстрактор
+func (sd *SyslogDecoder) Decode(data []byte) ([]actioner.Event, error) {
+	msg, err := ParseSyslog(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	event := actioner.Event{
+		Log:      msg.Message,
+		Priority: syslogSeverities[msg.Severity],
+		Time:     msg.Time,
+		Hostname: msg.Hostname,
+		Fields: map[string]string{
+			"syslog.facility": strconv.Itoa(msg.Facility),
+			"syslog.severity": strconv.Itoa(msg.Severity),
+			"syslog.app_name": msg.AppName,
+			"syslog.proc_id":  msg.ProcID,
+			"syslog.msg_id":   msg.MsgID,
+		},
+	}
+
+	for _, ex := range sd.extractors {
+		if ex.app != "" && ex.app != msg.AppName {
+			continue
+		}
+		m := ex.pattern.FindStringSubmatch(msg.Message)
+		if m == nil {
+			continue
+		}
+		// Група ip бере те, що написав відправник; не-адреса (наприклад, ім'я хоста) не повинна
+		// стати ключем блокування, тож такий збіг не рахується
+		if i := ex.pattern.SubexpIndex("ip"); i >= 0 && m[i] != "" && net.ParseIP(m[i]) == nil {
+			continue
+		}
+		event.RuleName = ex.rule
+		for i, name := range ex.pattern.SubexpNames() {
+			switch name {
+			case "":
+				continue
+			case "ip":
+				event.IP = m[i]
+			case "rule":
+				if m[i] != "" {
+					event.RuleName = m[i]
+				}
+			default:
+				event.Fields[name] = m[i]
+			}
+		}
+		if ex.priority != "" {
+			event.Priority = ex.priority
+		}
+		return []actioner.Event{event}, nil
+	}
+
+	if !sd.emitUnmatched {
+		return nil, nil
+	}
+	event.RuleName = msg.AppName
+	return []actioner.Event{event}, nil
+}
+
+// Name - повертає ім'я декодера
+func (sd *SyslogDecoder) Name() string { return "syslog" }
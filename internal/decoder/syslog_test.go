@@ -0,0 +1,118 @@
+package decoder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSyslog(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    SyslogMessage
+		wantErr bool
+	}{
+		{
+			name: "rfc5424",
+			line: "<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su 77 ID47 - 'su root' failed for lonvick on /dev/pts/8",
+			want: SyslogMessage{Facility: 4, Severity: 2, Time: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname: "mymachine.example.com", AppName: "su", ProcID: "77", MsgID: "ID47", Message: "'su root' failed for lonvick on /dev/pts/8"},
+		},
+		{
+			name: "rfc5424 structured data and bom",
+			line: "<165>1 2003-10-11T22:14:15.003Z host evntslog - ID47 [exampleSDID@32473 iut=\"3\" eventID=\"1011\"][meta a=\"\\]\"] \ufeffAn application event",
+			want: SyslogMessage{Facility: 20, Severity: 5, Time: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname: "host", AppName: "evntslog", MsgID: "ID47", Message: "An application event"},
+		},
+		{
+			name: "rfc5424 nil values without message",
+			line: "<13>1 - - - - - -",
+			want: SyslogMessage{Facility: 1, Severity: 5},
+		},
+		{
+			name: "rfc5424 bad timestamp keeps message",
+			line: "<13>1 yesterday host app - - - text",
+			want: SyslogMessage{Facility: 1, Severity: 5, Hostname: "host", AppName: "app", Message: "text"},
+		},
+		{
+			name: "rfc3164",
+			line: "<38>Oct  9 22:33:20 web01 sshd[4242]: Failed password for root from 203.0.113.5 port 22 ssh2\r\n",
+			want: SyslogMessage{Facility: 4, Severity: 6, Hostname: "web01", AppName: "sshd", ProcID: "4242",
+				Message: "Failed password for root from 203.0.113.5 port 22 ssh2"},
+		},
+		{
+			name: "rfc3164 without pid",
+			line: "<13>Feb 28 01:02:03 host kernel: oops",
+			want: SyslogMessage{Facility: 1, Severity: 5, Hostname: "host", AppName: "kernel", Message: "oops"},
+		},
+		{name: "empty", line: "", wantErr: true},
+		{name: "no pri", line: "Oct  9 22:33:20 web01 sshd[4242]: hello", wantErr: true},
+		{name: "unclosed pri", line: "<34 1 2003-10-11T22:14:15Z host app - - - msg", wantErr: true},
+		{name: "pri out of range", line: "<192>1 2003-10-11T22:14:15Z host app - - - msg", wantErr: true},
+		{name: "truncated rfc5424 header", line: "<34>1 2003-10-11T22:14:15.003Z mymachine su", wantErr: true},
+		{name: "truncated rfc5424 structured data", line: "<34>1 2003-10-11T22:14:15Z host app - - [id a=\"1\"", wantErr: true},
+		{name: "truncated rfc3164 timestamp", line: "<13>Feb 28 01:02", wantErr: true},
+		{name: "rfc3164 without tag", line: "<13>Feb 28 01:02:03 host", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSyslog(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.want.Time.IsZero() && !got.Time.Equal(tt.want.Time) {
+				t.Errorf("time = %s, want %s", got.Time, tt.want.Time)
+			}
+			got.Time, tt.want.Time = time.Time{}, time.Time{}
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyslogDecoderExtractors(t *testing.T) {
+	dec, err := NewSyslogDecoder(map[string]interface{}{
+		"extractors": []interface{}{
+			map[string]interface{}{
+				"app":     "sshd",
+				"pattern": `Failed password for (invalid user )?(?P<user>\S+) from (?P<ip>\S+) port \d+`,
+				"rule":    "SSH Failed Password",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSyslogDecoder: %v", err)
+	}
+
+	events, err := dec.Decode([]byte("<38>Oct  9 22:33:20 web01 sshd[4242]: Failed password for invalid user admin from 203.0.113.5 port 22 ssh2"))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.IP != "203.0.113.5" || e.RuleName != "SSH Failed Password" || e.Field("user") != "admin" || e.Priority != "Informational" {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	for _, line := range []string{
+		"<38>Oct  9 22:33:20 web01 cron[1]: Failed password for root from 203.0.113.5 port 22",     // Інша програма
+		"<38>Oct  9 22:33:20 web01 sshd[4242]: Accepted password for root",                         // Шаблон не збігся
+		"<38>Oct  9 22:33:20 web01 sshd[4242]: Failed password for root from evil.example port 22", // Група ip - не адреса
+	} {
+		if events, err := dec.Decode([]byte(line)); err != nil || len(events) != 0 {
+			t.Errorf("Decode(%q) = %v, %v; want no events", line, events, err)
+		}
+	}
+	if _, err := dec.Decode([]byte("not syslog")); err == nil {
+		t.Errorf("Decode accepted a malformed message")
+	}
+}
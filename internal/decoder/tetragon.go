@@ -0,0 +1,291 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+)
+
+func init() {
+	Register("tetragon", func(cfg Config) (Decoder, error) {
+		return NewTetragonDecoder(cfg.Params)
+	})
+}
+
+// TetragonProcess - процес у події Tetragon
+type TetragonProcess struct {
+	ExecID    string `json:"exec_id"`
+	PID       uint32 `json:"pid"`
+	UID       uint32 `json:"uid"`
+	Cwd       string `json:"cwd"`
+	Binary    string `json:"binary"`
+	Arguments string `json:"arguments"`
+	Docker    string `json:"docker"`
+	Pod       *struct {
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+		Container *struct {
+			ID    string `json:"id"`
+			Name  string `json:"name"`
+			Image *struct {
+				Name string `json:"name"`
+			} `json:"image"`
+		} `json:"container"`
+		Workload     string `json:"workload"`
+		WorkloadKind string `json:"workload_kind"`
+	} `json:"pod"`
+}
+
+// TetragonSockArg - аргумент-сокет у kprobe-події (tcp_connect тощо)
+type TetragonSockArg struct {
+	Family   string `json:"family"`
+	Protocol string `json:"protocol"`
+	Saddr    string `json:"saddr"`
+	Daddr    string `json:"daddr"`
+	Sport    uint32 `json:"sport"`
+	Dport    uint32 `json:"dport"`
+}
+
+// TetragonArg - аргумент kprobe/tracepoint-події
+type TetragonArg struct {
+	SockArg     *TetragonSockArg `json:"sock_arg"`
+	SockaddrArg *struct {
+		Family string `json:"family"`
+		Addr   string `json:"addr"`
+		Port   uint32 `json:"port"`
+	} `json:"sockaddr_arg"`
+}
+
+// TetragonPolicyEvent - kprobe/tracepoint-подія, що спрацювала за політикою
+type TetragonPolicyEvent struct {
+	Process      TetragonProcess  `json:"process"`
+	Parent       *TetragonProcess `json:"parent"`
+	FunctionName string           `json:"function_name"`
+	Subsys       string           `json:"subsys"`
+	Event        string           `json:"event"`
+	Args         []TetragonArg    `json:"args"`
+	Action       string           `json:"action"`
+	PolicyName   string           `json:"policy_name"`
+}
+
+// TetragonEvent - рядок JSON-експорту Tetragon
+type TetragonEvent struct {
+	ProcessExec *struct {
+		Process TetragonProcess  `json:"process"`
+		Parent  *TetragonProcess `json:"parent"`
+	} `json:"process_exec"`
+	ProcessExit *struct {
+		Process TetragonProcess  `json:"process"`
+		Parent  *TetragonProcess `json:"parent"`
+		Signal  string           `json:"signal"`
+		Status  uint32           `json:"status"`
+	} `json:"process_exit"`
+	ProcessConnect *struct {
+		Process         TetragonProcess  `json:"process"`
+		Parent          *TetragonProcess `json:"parent"`
+		SourceIP        string           `json:"source_ip"`
+		SourcePort      uint32           `json:"source_port"`
+		DestinationIP   string           `json:"destination_ip"`
+		DestinationPort uint32           `json:"destination_port"`
+		Protocol        string           `json:"protocol"`
+	} `json:"process_connect"`
+	ProcessKprobe     *TetragonPolicyEvent `json:"process_kprobe"`
+	ProcessTracepoint *TetragonPolicyEvent `json:"process_tracepoint"`
+	NodeName          string               `json:"node_name"`
+	Time              string               `json:"time"`
+}
+
+// TetragonDecoder - декодер JSON-експорту Cilium Tetragon
+type TetragonDecoder struct {
+	eventTypes map[string]bool
+}
+
+// NewTetragonDecoder - створює новий TetragonDecoder
+func NewTetragonDecoder(params map[string]interface{}) (*TetragonDecoder, error) {
+	td := &TetragonDecoder{}
+	types, err := stringsParam(params, "event_types")
+	if err != nil {
+		return nil, err
+	}
+	if len(types) > 0 {
+		td.eventTypes = make(map[string]bool, len(types))
+		for _, t := range types {
+			td.eventTypes[t] = true
+		}
+	}
+	return td, nil
+}
+
+// Decode - розбирає подію Tetragon
+func (td *TetragonDecoder) Decode(data []byte) ([]actioner.Event, error) {
+	var te TetragonEvent
+	if err := json.Unmarshal(data, &te); err != nil {
+		return nil, fmt.Errorf("invalid tetragon event: %v", err)
+	}
+
+	event := actioner.Event{
+		Hostname: te.NodeName,
+		Fields:   make(map[string]string),
+	}
+	if t, err := time.Parse(time.RFC3339Nano, te.Time); err == nil {
+		event.Time = t
+	}
+
+	var eventType string
+	switch {
+	case te.ProcessExec != nil:
+		eventType = "process_exec"
+		event.RuleName = eventType
+		event.Priority = "Informational"
+		addProcessFields(event.Fields, "process", &te.ProcessExec.Process)
+		addProcessFields(event.Fields, "parent", te.ProcessExec.Parent)
+	case te.ProcessExit != nil:
+		eventType = "process_exit"
+		event.RuleName = eventType
+		event.Priority = "Informational"
+		addProcessFields(event.Fields, "process", &te.ProcessExit.Process)
+		addProcessFields(event.Fields, "parent", te.ProcessExit.Parent)
+		if te.ProcessExit.Signal != "" {
+			event.Fields["exit.signal"] = te.ProcessExit.Signal
+		}
+		event.Fields["exit.status"] = strconv.FormatUint(uint64(te.ProcessExit.Status), 10)
+	case te.ProcessConnect != nil:
+		pc := te.ProcessConnect
+		eventType = "process_connect"
+		event.RuleName = eventType
+		event.Priority = "Notice"
+		event.IP = pc.DestinationIP
+		addProcessFields(event.Fields, "process", &pc.Process)
+		addProcessFields(event.Fields, "parent", pc.Parent)
+		event.Fields["connect.saddr"] = pc.SourceIP
+		event.Fields["connect.sport"] = strconv.FormatUint(uint64(pc.SourcePort), 10)
+		event.Fields["connect.daddr"] = pc.DestinationIP
+		event.Fields["connect.dport"] = strconv.FormatUint(uint64(pc.DestinationPort), 10)
+		if pc.Protocol != "" {
+			event.Fields["connect.protocol"] = pc.Protocol
+		}
+	case te.ProcessKprobe != nil:
+		eventType = "process_kprobe"
+		td.policyEvent(&event, te.ProcessKprobe, te.ProcessKprobe.FunctionName)
+	case te.ProcessTracepoint != nil:
+		eventType = "process_tracepoint"
+		tp := te.ProcessTracepoint
+		td.policyEvent(&event, tp, tp.Subsys+"/"+tp.Event)
+	default:
+		return nil, nil // Непідтримуваний тип події
+	}
+
+	if td.eventTypes != nil && !td.eventTypes[eventType] {
+		return nil, nil
+	}
+	event.Fields["tetragon.event_type"] = eventType
+	if uid := userUID(te.NodeName, event.Fields["process.uid"]); uid != "" {
+		event.Fields["user.uid"] = uid
+	}
+
+	compact := new(bytes.Buffer)
+	if err := json.Compact(compact, data); err != nil {
+		compact.Reset()
+		compact.Write(data)
+	}
+	event.Log = compact.String()
+	return []actioner.Event{event}, nil
+}
+
+// userUID - значення user.uid для ключа кореляції user: uid має сенс лише в межах вузла, тож
+// він записується як "<вузол>/<uid>". Для uid 0 (root, а також процеси без uid у події) і
+// невідомого вузла ключ не заповнюється - інакше всі процеси root з усіх контейнерів
+// корелювали б як один користувач; такі події краще групувати за container, pod чи host
+func userUID(node, uid string) string {
+	if node == "" || uid == "" || uid == "0" {
+		return ""
+	}
+	return node + "/" + uid
+}
+
+// policyEvent - заповнює подію з kprobe/tracepoint-події; ім'я політики стає правилом
+func (td *TetragonDecoder) policyEvent(event *actioner.Event, pe *TetragonPolicyEvent, function string) {
+	event.RuleName = pe.PolicyName
+	if event.RuleName == "" {
+		event.RuleName = function
+	}
+	event.Priority = "Warning"
+	if strings.Contains(pe.Action, "SIGKILL") || strings.Contains(pe.Action, "OVERRIDE") {
+		event.Priority = "Critical"
+	}
+	addProcessFields(event.Fields, "process", &pe.Process)
+	addProcessFields(event.Fields, "parent", pe.Parent)
+	event.Fields["policy.name"] = pe.PolicyName
+	event.Fields["policy.function"] = function
+	event.Fields["policy.action"] = pe.Action
+
+	for _, arg := range pe.Args {
+		switch {
+		case arg.SockArg != nil:
+			sa := arg.SockArg
+			event.IP = sa.Daddr
+			event.Fields["connect.saddr"] = sa.Saddr
+			event.Fields["connect.sport"] = strconv.FormatUint(uint64(sa.Sport), 10)
+			event.Fields["connect.daddr"] = sa.Daddr
+			event.Fields["connect.dport"] = strconv.FormatUint(uint64(sa.Dport), 10)
+			event.Fields["connect.protocol"] = sa.Protocol
+		case arg.SockaddrArg != nil:
+			event.IP = arg.SockaddrArg.Addr
+			event.Fields["connect.daddr"] = arg.SockaddrArg.Addr
+			event.Fields["connect.dport"] = strconv.FormatUint(uint64(arg.SockaddrArg.Port), 10)
+		default:
+			continue
+		}
+		break
+	}
+}
+
+// Name - повертає ім'я декодера
+func (td *TetragonDecoder) Name() string { return "tetragon" }
+
+// addProcessFields - додає поля процесу та його пода з префіксом
+func addProcessFields(fields map[string]string, prefix string, p *TetragonProcess) {
+	if p == nil {
+		return
+	}
+	fields[prefix+".binary"] = p.Binary
+	fields[prefix+".arguments"] = p.Arguments
+	fields[prefix+".pid"] = strconv.FormatUint(uint64(p.PID), 10)
+	fields[prefix+".uid"] = strconv.FormatUint(uint64(p.UID), 10)
+	fields[prefix+".cwd"] = p.Cwd
+	fields[prefix+".exec_id"] = p.ExecID
+	if prefix != "process" {
+		return
+	}
+
+	// Метадані пода та контейнера - у тих самих ключах, що й у Falco
+	if p.Docker != "" {
+		fields["container.id"] = p.Docker
+	}
+	if p.Pod == nil {
+		return
+	}
+	fields["k8s.pod.name"] = p.Pod.Name
+	fields["k8s.ns.name"] = p.Pod.Namespace
+	if p.Pod.Workload != "" {
+		fields["k8s.workload"] = p.Pod.WorkloadKind + "/" + p.Pod.Workload
+	}
+	if c := p.Pod.Container; c != nil {
+		if _, ok := fields["container.id"]; !ok {
+			id := c.ID
+			if i := strings.Index(id, "://"); i >= 0 {
+				id = id[i+3:]
+			}
+			fields["container.id"] = id
+		}
+		fields["container.name"] = c.Name
+		if c.Image != nil {
+			fields["container.image"] = c.Image.Name
+		}
+	}
+}
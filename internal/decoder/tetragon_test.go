@@ -0,0 +1,147 @@
+package decoder
+
+import "testing"
+
+func TestTetragonDecoder(t *testing.T) {
+	exec := []byte(`{"process_exec":{"process":{"exec_id":"ZXhlYw==","pid":812,"uid":1000,"cwd":"/home/app","binary":"/bin/bash",` +
+		`"arguments":"-i","pod":{"namespace":"web","name":"frontend-7d9f","container":{"id":"containerd://77ab","name":"nginx"}}},` +
+		`"parent":{"pid":800,"binary":"/usr/sbin/sshd"}},"node_name":"kind-worker","time":"2024-05-01T10:19:00Z"}`)
+	connect := []byte(`{"process_connect":{"process":{"pid":900,"binary":"/usr/bin/nc"},"source_ip":"10.0.1.12","source_port":40100,` +
+		`"destination_ip":"203.0.113.9","destination_port":22,"protocol":"TCP"},"node_name":"kind-worker","time":"2024-05-01T10:19:30Z"}`)
+	tests := []struct {
+		name       string
+		params     map[string]interface{}
+		data       []byte
+		wantEvents int
+		wantIP     string
+		wantRule   string
+		wantFields map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "kprobe tcp_connect",
+			data:       readSample(t, "tetragon_kprobe.json"),
+			wantEvents: 1,
+			wantIP:     "198.51.100.23",
+			wantRule:   "block-reverse-shell", // Ім'я політики
+			wantFields: map[string]string{
+				"tetragon.event_type": "process_kprobe",
+				"policy.function":     "tcp_connect",
+				"policy.action":       "KPROBE_ACTION_SIGKILL",
+				"connect.dport":       "4444",
+				"process.binary":      "/usr/bin/curl",
+				"parent.binary":       "/bin/sh",
+				"container.id":        "9e4b1f0c2d7a4f1", // docker має перевагу над id контейнера пода
+				"k8s.pod.name":        "frontend-7d9f",
+				"k8s.workload":        "Deployment/frontend",
+				"container.image":     "docker.io/library/nginx:1.25",
+			},
+		},
+		{
+			// Подія exec без мережі не має IP
+			name:       "process_exec",
+			data:       exec,
+			wantEvents: 1,
+			wantRule:   "process_exec",
+			wantFields: map[string]string{
+				"process.uid":  "1000",
+				"user.uid":     "kind-worker/1000", // uid має сенс лише в межах вузла
+				"container.id": "77ab",             // Без префікса середовища виконання
+				"k8s.ns.name":  "web",
+			},
+		},
+		{
+			name:       "process_connect",
+			data:       connect,
+			wantEvents: 1,
+			wantIP:     "203.0.113.9",
+			wantRule:   "process_connect",
+			wantFields: map[string]string{"connect.saddr": "10.0.1.12", "connect.protocol": "TCP", "user.uid": ""}, // uid 0 не корелюється за user
+		},
+		{
+			name:   "event type filtered",
+			params: map[string]interface{}{"event_types": []interface{}{"process_kprobe"}},
+			data:   exec,
+		},
+		{
+			name:       "event type kept",
+			params:     map[string]interface{}{"event_types": []interface{}{"process_kprobe"}},
+			data:       readSample(t, "tetragon_kprobe.json"),
+			wantEvents: 1,
+			wantIP:     "198.51.100.23",
+			wantRule:   "block-reverse-shell",
+		},
+		{
+			name: "unsupported event",
+			data: []byte(`{"process_loader":{"process":{"pid":1}},"node_name":"kind-worker"}`),
+		},
+		{name: "malformed json", data: []byte(`{"process_exec":`), wantErr: true},
+		{name: "wrong field type", data: []byte(`{"process_exec":{"process":{"pid":"812"}}}`), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td, err := NewTetragonDecoder(tt.params)
+			if err != nil {
+				t.Fatalf("NewTetragonDecoder: %v", err)
+			}
+			events, err := td.Decode(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(events) != tt.wantEvents {
+				t.Fatalf("got %d events, want %d", len(events), tt.wantEvents)
+			}
+			if tt.wantEvents == 0 {
+				return
+			}
+			e := events[0]
+			if e.IP != tt.wantIP || e.RuleName != tt.wantRule {
+				t.Errorf("IP, RuleName = %q, %q; want %q, %q", e.IP, e.RuleName, tt.wantIP, tt.wantRule)
+			}
+			for k, want := range tt.wantFields {
+				if got := e.Field(k); got != want {
+					t.Errorf("Field(%q) = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTetragonDecoderPriority(t *testing.T) {
+	tests := []struct {
+		action string
+		want   string
+	}{
+		{"KPROBE_ACTION_SIGKILL", "Critical"},
+		{"KPROBE_ACTION_OVERRIDE", "Critical"},
+		{"KPROBE_ACTION_POST", "Warning"},
+		{"", "Warning"},
+	}
+	for _, tt := range tests {
+		data := []byte(`{"process_kprobe":{"process":{"pid":1},"function_name":"security_file_open","action":"` + tt.action + `"}}`)
+		events, err := (&TetragonDecoder{}).Decode(data)
+		if err != nil || len(events) != 1 {
+			t.Fatalf("Decode(%s) = %v, %v", tt.action, events, err)
+		}
+		if events[0].Priority != tt.want || events[0].RuleName != "security_file_open" {
+			t.Errorf("action %q: Priority, RuleName = %q, %q; want %q, security_file_open", tt.action, events[0].Priority, events[0].RuleName, tt.want)
+		}
+	}
+}
+
+func TestTetragonUserUID(t *testing.T) {
+	tests := []struct {
+		node, uid string
+		want      string
+	}{
+		{"kind-worker", "1000", "kind-worker/1000"},
+		{"kind-worker", "0", ""}, // root на вузлі - не один користувач
+		{"", "1000", ""},
+		{"kind-worker", "", ""},
+	}
+	for _, tt := range tests {
+		if got := userUID(tt.node, tt.uid); got != tt.want {
+			t.Errorf("userUID(%q, %q) = %q, want %q", tt.node, tt.uid, got, tt.want)
+		}
+	}
+}
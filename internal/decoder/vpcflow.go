@@ -0,0 +1,353 @@
+package decoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+)
+
+func init() {
+	Register("vpc_flow", func(cfg Config) (Decoder, error) {
+		return NewVPCFlowDecoder(cfg.Params)
+	})
+}
+
+// VPCFlowRecord - запис VPC Flow Logs (jsonPayload)
+type VPCFlowRecord struct {
+	Connection struct {
+		SrcIP    string      `json:"src_ip"`
+		SrcPort  json.Number `json:"src_port"`
+		DestIP   string      `json:"dest_ip"`
+		DestPort json.Number `json:"dest_port"`
+		Protocol json.Number `json:"protocol"`
+	} `json:"connection"`
+	Reporter     string      `json:"reporter"`
+	StartTime    string      `json:"start_time"`
+	EndTime      string      `json:"end_time"`
+	BytesSent    json.Number `json:"bytes_sent"`
+	PacketsSent  json.Number `json:"packets_sent"`
+	DestInstance *struct {
+		VMName    string `json:"vm_name"`
+		ProjectID string `json:"project_id"`
+		Zone      string `json:"zone"`
+	} `json:"dest_instance"`
+	SrcLocation *struct {
+		Country string `json:"country"`
+	} `json:"src_location"`
+}
+
+// vpcLogEntry - запис Cloud Logging з VPC Flow Logs
+type vpcLogEntry struct {
+	JSONPayload *VPCFlowRecord `json:"jsonPayload"`
+	Timestamp   string         `json:"timestamp"`
+	LogName     string         `json:"logName"`
+}
+
+// VPCFlowDecoder - декодер VPC Flow Logs з вбудованими детекторами сканування портів і флуду
+type VPCFlowDecoder struct {
+	emitFlows bool
+	flowRule  string
+	scan      *portScanDetector
+	flood     *floodDetector
+}
+
+// NewVPCFlowDecoder - створює новий VPCFlowDecoder
+func NewVPCFlowDecoder(params map[string]interface{}) (*VPCFlowDecoder, error) {
+	vd := &VPCFlowDecoder{}
+	var err error
+	if vd.emitFlows, err = boolParam(params, "emit_flows", false); err != nil {
+		return nil, err
+	}
+	if vd.flowRule, err = stringParam(params, "flow_rule", "VPC Flow"); err != nil {
+		return nil, err
+	}
+
+	scanParams, err := mapParam(params, "port_scan")
+	if err != nil {
+		return nil, err
+	}
+	if scanParams != nil {
+		vd.scan = &portScanDetector{sources: make(map[string]*scanState)}
+		if vd.scan.threshold, err = intParam(scanParams, "distinct_ports", 20); err != nil {
+			return nil, err
+		}
+		if vd.scan.window, err = durationParam(scanParams, "window", time.Minute); err != nil {
+			return nil, err
+		}
+		if vd.scan.rule, err = stringParam(scanParams, "rule", "VPC Port Scan"); err != nil {
+			return nil, err
+		}
+	}
+
+	floodParams, err := mapParam(params, "connection_flood")
+	if err != nil {
+		return nil, err
+	}
+	if floodParams != nil {
+		vd.flood = &floodDetector{targets: make(map[string]*floodState)}
+		if vd.flood.threshold, err = intParam(floodParams, "connections", 100); err != nil {
+			return nil, err
+		}
+		if vd.flood.window, err = durationParam(floodParams, "window", time.Minute); err != nil {
+			return nil, err
+		}
+		if vd.flood.rule, err = stringParam(floodParams, "rule", "VPC Connection Flood"); err != nil {
+			return nil, err
+		}
+	}
+
+	if !vd.emitFlows && vd.scan == nil && vd.flood == nil {
+		return nil, fmt.Errorf("vpc_flow decoder needs emit_flows, port_scan or connection_flood")
+	}
+	return vd, nil
+}
+
+// Decode - розбирає запис VPC Flow Logs і повертає виявлені атаки (та, за потреби, сам потік)
+func (vd *VPCFlowDecoder) Decode(data []byte) ([]actioner.Event, error) {
+	var entry vpcLogEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("invalid VPC flow log entry: %v", err)
+	}
+	rec := entry.JSONPayload
+	if rec == nil {
+		// Запис без обгортки Cloud Logging
+		rec = new(VPCFlowRecord)
+		if err := json.Unmarshal(data, rec); err != nil {
+			return nil, fmt.Errorf("invalid VPC flow record: %v", err)
+		}
+	}
+	if rec.Connection.SrcIP == "" {
+		return nil, fmt.Errorf("invalid VPC flow record: missing connection.src_ip")
+	}
+
+	ts := parseTime(rec.StartTime)
+	if ts.IsZero() {
+		ts = parseTime(entry.Timestamp)
+	}
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	flow := actioner.Event{
+		IP:       rec.Connection.SrcIP,
+		RuleName: vd.flowRule,
+		Priority: "Informational",
+		Time:     ts,
+		Fields: map[string]string{
+			"connection.src_ip":    rec.Connection.SrcIP,
+			"connection.src_port":  rec.Connection.SrcPort.String(),
+			"connection.dest_ip":   rec.Connection.DestIP,
+			"connection.dest_port": rec.Connection.DestPort.String(),
+			"connection.protocol":  vpcProtocol(rec.Connection.Protocol),
+			"flow.reporter":        rec.Reporter,
+			"flow.bytes_sent":      rec.BytesSent.String(),
+			"flow.packets_sent":    rec.PacketsSent.String(),
+		},
+	}
+	if rec.DestInstance != nil {
+		flow.Hostname = rec.DestInstance.VMName
+		flow.Fields["dest_instance.project_id"] = rec.DestInstance.ProjectID
+		flow.Fields["dest_instance.zone"] = rec.DestInstance.Zone
+	}
+	if rec.SrcLocation != nil {
+		flow.Fields["src_location.country"] = rec.SrcLocation.Country
+	}
+	flow.Log = fmt.Sprintf("%s %s:%s -> %s:%s", flow.Fields["connection.protocol"],
+		rec.Connection.SrcIP, rec.Connection.SrcPort, rec.Connection.DestIP, rec.Connection.DestPort)
+
+	var events []actioner.Event
+	if vd.emitFlows {
+		events = append(events, flow)
+	}
+	if vd.scan != nil {
+		if ports, ok := vd.scan.observe(rec.Connection.SrcIP, flow.Fields["connection.dest_port"], ts); ok {
+			events = append(events, detection(flow, vd.scan.rule,
+				fmt.Sprintf("Port scan from %s: %d distinct destination ports within %s", flow.IP, ports, vd.scan.window),
+				map[string]string{"detector.distinct_ports": strconv.Itoa(ports), "detector.window": vd.scan.window.String()}))
+		}
+	}
+	// Потік між двома VM у VPC записується двічі - з боку джерела (SRC) і призначення (DEST),
+	// тож для підрахунку з'єднань береться лише один бік. Атаки ззовні записує лише призначення
+	if vd.flood != nil && rec.Reporter != "SRC" {
+		// Рахуються з'єднання до порту на будь-яких адресах призначення, тож розкид по хостах
+		// підмережі (наприклад, перебір SSH) не ховає флуд
+		port := flow.Fields["connection.dest_port"]
+		if conns, ok := vd.flood.observe(rec.Connection.SrcIP+"->"+port, ts); ok {
+			events = append(events, detection(flow, vd.flood.rule,
+				fmt.Sprintf("Connection flood from %s to port %s: %d connections within %s", flow.IP, port, conns, vd.flood.window),
+				map[string]string{"detector.connections": strconv.Itoa(conns), "detector.window": vd.flood.window.String()}))
+		}
+	}
+	return events, nil
+}
+
+// Name - повертає ім'я декодера
+func (vd *VPCFlowDecoder) Name() string { return "vpc_flow" }
+
+// detection - створює синтетичну подію детектора на основі потоку
+func detection(flow actioner.Event, rule, summary string, extra map[string]string) actioner.Event {
+	event := flow
+	event.RuleName = rule
+	event.Priority = "Warning"
+	event.Log = summary
+	event.Fields = make(map[string]string, len(flow.Fields)+len(extra))
+	for k, v := range flow.Fields {
+		event.Fields[k] = v
+	}
+	for k, v := range extra {
+		event.Fields[k] = v
+	}
+	return event
+}
+
+// vpcProtocol - перетворює номер IP-протоколу на назву
+func vpcProtocol(n json.Number) string {
+	switch n.String() {
+	case "1":
+		return "ICMP"
+	case "6":
+		return "TCP"
+	case "17":
+		return "UDP"
+	case "58":
+		return "ICMPv6"
+	default:
+		return n.String()
+	}
+}
+
+// scanState - порти, до яких звертався одне джерело, та час останнього спрацювання
+type scanState struct {
+	ports     map[string]time.Time
+	lastAlert time.Time
+	lastSeen  time.Time
+}
+
+// portScanDetector - виявляє багато різних портів призначення від одного джерела у вікні
+type portScanDetector struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	rule      string
+	sources   map[string]*scanState
+	calls     int
+}
+
+// observe - враховує з'єднання; повертає кількість портів, якщо поріг досягнуто
+func (d *portScanDetector) observe(src, port string, ts time.Time) (int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.calls++
+	if d.calls%1000 == 0 {
+		for key, st := range d.sources {
+			if ts.Sub(st.lastSeen) > d.window {
+				delete(d.sources, key)
+			}
+		}
+	}
+
+	st, ok := d.sources[src]
+	if !ok {
+		st = &scanState{ports: make(map[string]time.Time)}
+		d.sources[src] = st
+	}
+	if ts.After(st.lastSeen) {
+		st.lastSeen = ts
+	}
+	st.ports[port] = ts
+	for p, seen := range st.ports {
+		if ts.Sub(seen) > d.window {
+			delete(st.ports, p)
+		}
+	}
+
+	// Не повторюємо спрацювання частіше, ніж раз на вікно
+	if len(st.ports) < d.threshold || (!st.lastAlert.IsZero() && ts.Sub(st.lastAlert) < d.window) {
+		return len(st.ports), false
+	}
+	st.lastAlert = ts
+	return len(st.ports), true
+}
+
+// floodSlots - на скільки проміжків ділиться вікно детектора флуду; лічильник з'єднань
+// ковзає з точністю до проміжку, зате пам'ять на ключ не залежить від кількості з'єднань
+const floodSlots = 10
+
+// floodState - лічильники з'єднань від джерела до одного порту за проміжками вікна
+// (кільцевий буфер) та час останнього спрацювання
+type floodState struct {
+	slots     [floodSlots]floodSlot
+	latest    int64 // Номер найпізнішого проміжку з з'єднаннями
+	lastAlert time.Time
+}
+
+// floodSlot - кількість з'єднань у проміжку з номером index
+type floodSlot struct {
+	index int64
+	count int
+}
+
+// floodDetector - виявляє багато з'єднань від джерела до одного порту призначення (на будь-якій адресі) у вікні
+type floodDetector struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	rule      string
+	targets   map[string]*floodState
+	calls     int
+}
+
+// observe - враховує з'єднання; повертає кількість з'єднань, якщо поріг досягнуто
+func (d *floodDetector) observe(key string, ts time.Time) (int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	slotLen := int64(d.window / floodSlots)
+	if slotLen <= 0 {
+		slotLen = 1
+	}
+	index := ts.UnixNano() / slotLen
+
+	d.calls++
+	if d.calls%1000 == 0 {
+		for k, st := range d.targets {
+			if index-st.latest >= floodSlots {
+				delete(d.targets, k)
+			}
+		}
+	}
+
+	st, ok := d.targets[key]
+	if !ok {
+		st = &floodState{latest: index}
+		d.targets[key] = st
+	}
+	if index > st.latest {
+		st.latest = index
+	}
+	// Запізніле з'єднання, старше за вікно, вже не враховується
+	if st.latest-index < floodSlots {
+		slot := &st.slots[(index%floodSlots+floodSlots)%floodSlots] // Час до 1970 дає від'ємний номер
+		if slot.index != index {
+			*slot = floodSlot{index: index}
+		}
+		slot.count++
+	}
+	conns := 0
+	for _, slot := range st.slots {
+		if st.latest-slot.index < floodSlots {
+			conns += slot.count
+		}
+	}
+
+	if conns < d.threshold || (!st.lastAlert.IsZero() && ts.Sub(st.lastAlert) < d.window) {
+		return conns, false
+	}
+	st.lastAlert = ts
+	return conns, true
+}
@@ -0,0 +1,160 @@
+package decoder
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPortScanDetector(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	type flow struct {
+		src  string
+		port string
+		at   time.Duration // Від base
+	}
+	tests := []struct {
+		name   string
+		flows  []flow
+		alerts []bool // Очікуване спрацювання після кожного потоку
+	}{
+		{
+			name:   "threshold reached",
+			flows:  []flow{{"10.0.0.1", "22", 0}, {"10.0.0.1", "80", time.Second}, {"10.0.0.1", "443", 2 * time.Second}},
+			alerts: []bool{false, false, true},
+		},
+		{
+			name:   "repeated port counted once",
+			flows:  []flow{{"10.0.0.1", "22", 0}, {"10.0.0.1", "22", time.Second}, {"10.0.0.1", "22", 2 * time.Second}},
+			alerts: []bool{false, false, false},
+		},
+		{
+			name:   "ports of other sources not counted",
+			flows:  []flow{{"10.0.0.1", "22", 0}, {"10.0.0.2", "80", time.Second}, {"10.0.0.3", "443", 2 * time.Second}},
+			alerts: []bool{false, false, false},
+		},
+		{
+			name:   "ports outside window expire",
+			flows:  []flow{{"10.0.0.1", "22", 0}, {"10.0.0.1", "80", time.Second}, {"10.0.0.1", "443", 90 * time.Second}},
+			alerts: []bool{false, false, false},
+		},
+		{
+			name: "one alert per window",
+			flows: []flow{{"10.0.0.1", "22", 0}, {"10.0.0.1", "80", 0}, {"10.0.0.1", "443", 0},
+				{"10.0.0.1", "8080", 30 * time.Second}, {"10.0.0.1", "8443", 50 * time.Second}, {"10.0.0.1", "9000", 61 * time.Second}},
+			alerts: []bool{false, false, true, false, false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &portScanDetector{threshold: 3, window: time.Minute, sources: make(map[string]*scanState)}
+			for i, f := range tt.flows {
+				if _, ok := d.observe(f.src, f.port, base.Add(f.at)); ok != tt.alerts[i] {
+					t.Errorf("flow %d (%s:%s): alert = %v, want %v", i, f.src, f.port, ok, tt.alerts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFloodDetector(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		times  []time.Duration // Від base
+		alerts []bool
+		conns  int // Кількість з'єднань після останнього
+	}{
+		{
+			name:   "threshold reached",
+			times:  []time.Duration{0, time.Second, 2 * time.Second},
+			alerts: []bool{false, false, true},
+			conns:  3,
+		},
+		{
+			name:   "connections outside window expire",
+			times:  []time.Duration{0, time.Second, 70 * time.Second},
+			alerts: []bool{false, false, false},
+			conns:  1,
+		},
+		{
+			name:   "late connection within window counted",
+			times:  []time.Duration{30 * time.Second, 40 * time.Second, 10 * time.Second},
+			alerts: []bool{false, false, true},
+			conns:  3,
+		},
+		{
+			name:   "late connection older than window ignored",
+			times:  []time.Duration{90 * time.Second, 95 * time.Second, 0},
+			alerts: []bool{false, false, false},
+			conns:  2,
+		},
+		{
+			name:   "one alert per window",
+			times:  []time.Duration{0, 0, 0, 30 * time.Second, 50 * time.Second, 61 * time.Second},
+			alerts: []bool{false, false, true, false, false, true},
+			conns:  3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &floodDetector{threshold: 3, window: time.Minute, targets: make(map[string]*floodState)}
+			conns := 0
+			for i, at := range tt.times {
+				var ok bool
+				conns, ok = d.observe("10.0.0.1->22", base.Add(at))
+				if ok != tt.alerts[i] {
+					t.Errorf("connection %d: alert = %v, want %v", i, ok, tt.alerts[i])
+				}
+			}
+			if conns != tt.conns {
+				t.Errorf("connections = %d, want %d", conns, tt.conns)
+			}
+		})
+	}
+}
+
+func TestFloodDetectorBoundedState(t *testing.T) {
+	d := &floodDetector{threshold: 1 << 30, window: time.Minute, targets: make(map[string]*floodState)}
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10000; i++ {
+		d.observe("10.0.0.1->22", base.Add(time.Duration(i)*time.Millisecond))
+	}
+	if conns, _ := d.observe("10.0.0.1->22", base.Add(10*time.Second)); conns != 10001 {
+		t.Errorf("connections = %d, want 10001", conns)
+	}
+	if conns, _ := d.observe("10.0.0.1->22", base.Add(5*time.Minute)); conns != 1 {
+		t.Errorf("connections after window = %d, want 1", conns)
+	}
+}
+
+func TestVPCFlowReporterCountedOnce(t *testing.T) {
+	vd, err := NewVPCFlowDecoder(map[string]interface{}{
+		"connection_flood": map[string]interface{}{"connections": 2, "window": "60s"},
+	})
+	if err != nil {
+		t.Fatalf("NewVPCFlowDecoder: %v", err)
+	}
+	record := func(reporter, srcPort string) []byte {
+		return []byte(fmt.Sprintf(`{"jsonPayload":{"connection":{"src_ip":"10.128.0.5","src_port":%s,"dest_ip":"10.128.0.9","dest_port":22,"protocol":6},`+
+			`"reporter":%q,"start_time":"2024-05-01T12:00:00Z"}}`, srcPort, reporter))
+	}
+
+	// Один потік між двома VM, записаний обома боками, - одне з'єднання
+	for _, reporter := range []string{"SRC", "DEST"} {
+		events, err := vd.Decode(record(reporter, "40000"))
+		if err != nil {
+			t.Fatalf("Decode %s: %v", reporter, err)
+		}
+		if len(events) != 0 {
+			t.Fatalf("%s record of the first connection raised %d events", reporter, len(events))
+		}
+	}
+	events, err := vd.Decode(record("DEST", "40001"))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(events) != 1 || events[0].RuleName != "VPC Connection Flood" || events[0].Fields["detector.connections"] != "2" {
+		t.Errorf("second connection: events = %+v, want one flood detection with 2 connections", events)
+	}
+}
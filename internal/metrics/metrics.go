@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// registry - лічильники та датчики рушія
+var registry = struct {
+	mu       sync.Mutex
+	counters map[string]int64
+	gauges   map[string]func() int64
+}{
+	counters: make(map[string]int64),
+	gauges:   make(map[string]func() int64),
+}
+
+// key - формує ім'я метрики з мітками у форматі Prometheus (labels - пари ключ/значення)
+func key(name string, labels []string) string {
+	if len(labels) < 2 {
+		return name
+	}
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[i+1])
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, labels[i], value))
+	}
+	return name + "{" + strings.Join(parts, ",") + "}"
+}
+
+// Inc - збільшує лічильник на 1
+func Inc(name string, labels ...string) {
+	Add(name, 1, labels...)
+}
+
+// Add - збільшує лічильник на delta
+func Add(name string, delta int64, labels ...string) {
+	k := key(name, labels)
+	registry.mu.Lock()
+	registry.counters[k] += delta
+	registry.mu.Unlock()
+}
+
+// Gauge - реєструє датчик, значення якого обчислюється під час збору метрик
+func Gauge(name string, f func() int64, labels ...string) {
+	k := key(name, labels)
+	registry.mu.Lock()
+	registry.gauges[k] = f
+	registry.mu.Unlock()
+}
+
+// Handler - віддає метрики у текстовому форматі Prometheus
+func Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		registry.mu.Lock()
+		lines := make([]string, 0, len(registry.counters)+len(registry.gauges))
+		for k, v := range registry.counters {
+			lines = append(lines, fmt.Sprintf("%s %d", k, v))
+		}
+		gauges := make(map[string]func() int64, len(registry.gauges))
+		for k, f := range registry.gauges {
+			gauges[k] = f
+		}
+		registry.mu.Unlock()
+
+		for k, f := range gauges {
+			lines = append(lines, fmt.Sprintf("%s %d", k, f()))
+		}
+		sort.Strings(lines)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintln(w, strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,53 @@
+package scenario
+
+import "github.com/cloudedugcp/responseEngine/internal/actioner"
+
+// DefaultKey - ключ кореляції за замовчуванням
+const DefaultKey = "ip"
+
+// correlationKeys - значення ключа кореляції події; поля спільні для декодерів (імена як у Falco)
+var correlationKeys = map[string]func(e actioner.Event) string{
+	"ip":        func(e actioner.Event) string { return e.IP },
+	"container": func(e actioner.Event) string { return e.Field("container.id") },
+	"pod": func(e actioner.Event) string {
+		if e.Field("k8s.pod.name") == "" {
+			return ""
+		}
+		return e.Field("k8s.ns.name") + "/" + e.Field("k8s.pod.name")
+	},
+	"namespace": func(e actioner.Event) string { return e.Field("k8s.ns.name") },
+	"host":      func(e actioner.Event) string { return e.Hostname },
+	"user": func(e actioner.Event) string {
+		for _, f := range []string{"user.name", "ka.user.name", "user.uid"} {
+			if v := e.Field(f); v != "" {
+				return v
+			}
+		}
+		return ""
+	},
+}
+
+// ValidKey - перевіряє, чи відомий ключ кореляції
+func ValidKey(key string) bool {
+	_, ok := correlationKeys[key]
+	return ok
+}
+
+// CorrelationKey - значення ключа кореляції події; порожнє, якщо подія його не містить
+func CorrelationKey(key string, event actioner.Event) string {
+	if f, ok := correlationKeys[key]; ok {
+		return f(event)
+	}
+	return ""
+}
+
+// CorrelationKeys - значення всіх ключів кореляції події, окрім IP
+func CorrelationKeys(event actioner.Event) map[string]string {
+	keys := make(map[string]string, len(correlationKeys)-1)
+	for key, f := range correlationKeys {
+		if key != "ip" {
+			keys[key] = f(event)
+		}
+	}
+	return keys
+}
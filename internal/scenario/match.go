@@ -0,0 +1,142 @@
+package scenario
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+)
+
+// priorityLevels - пріоритети Falco (і syslog) від найвищого до найнижчого
+var priorityLevels = map[string]int{
+	"emergency":     0,
+	"alert":         1,
+	"critical":      2,
+	"error":         3,
+	"warning":       4,
+	"notice":        5,
+	"informational": 6,
+	"info":          6,
+	"debug":         7,
+}
+
+// PriorityLevel - числовий рівень пріоритету (0 - Emergency, 7 - Debug); false, якщо пріоритет невідомий
+func PriorityLevel(priority string) (int, bool) {
+	level, ok := priorityLevels[strings.ToLower(strings.TrimSpace(priority))]
+	return level, ok
+}
+
+// RuleMatch - критерії відбору подій сценарієм, окрім falco_rule
+type RuleMatch struct {
+	Rules       []string `mapstructure:"rules"`        // Імена правил; підтримують шаблони * і ?
+	RuleRegex   []string `mapstructure:"rule_regex"`   // Регулярні вирази для імені правила
+	Tags        []string `mapstructure:"tags"`         // Достатньо, щоб подія мала один із тегів
+	MinPriority string   `mapstructure:"min_priority"` // Подія має бути не нижче цього пріоритету
+}
+
+// Matcher - скомпільовані критерії відбору подій
+type Matcher struct {
+	exact    map[string]bool
+	patterns []*regexp.Regexp
+	tags     map[string]bool
+	maxLevel int // -1 - без обмеження за пріоритетом
+}
+
+// NewMatcher - компілює критерії; falcoRule - точне ім'я правила (поле falco_rule)
+func NewMatcher(falcoRule string, m RuleMatch) (*Matcher, error) {
+	mt := &Matcher{exact: make(map[string]bool), maxLevel: -1}
+	if falcoRule != "" {
+		mt.exact[falcoRule] = true
+	}
+	for _, rule := range m.Rules {
+		if !strings.ContainsAny(rule, "*?") {
+			mt.exact[rule] = true
+			continue
+		}
+		mt.patterns = append(mt.patterns, globRegexp(rule))
+	}
+	for _, expr := range m.RuleRegex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule_regex %q: %v", expr, err)
+		}
+		mt.patterns = append(mt.patterns, re)
+	}
+	if len(m.Tags) > 0 {
+		mt.tags = make(map[string]bool, len(m.Tags))
+		for _, tag := range m.Tags {
+			mt.tags[tag] = true
+		}
+	}
+	if m.MinPriority != "" {
+		level, ok := PriorityLevel(m.MinPriority)
+		if !ok {
+			return nil, fmt.Errorf("unknown min_priority %q", m.MinPriority)
+		}
+		mt.maxLevel = level
+	}
+	if len(mt.exact) == 0 && len(mt.patterns) == 0 && mt.tags == nil && mt.maxLevel < 0 {
+		return nil, fmt.Errorf("no rule, tag or priority criteria")
+	}
+	return mt, nil
+}
+
+// globRegexp - перетворює шаблон з * і ? на регулярний вираз для всього імені
+func globRegexp(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// Match - перевіряє, чи відповідає подія всім заданим критеріям
+func (m *Matcher) Match(event actioner.Event) bool {
+	if len(m.exact) > 0 || len(m.patterns) > 0 {
+		if !m.matchRule(event.RuleName) {
+			return false
+		}
+	}
+	if m.tags != nil {
+		found := false
+		for _, tag := range event.Tags {
+			if m.tags[tag] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if m.maxLevel >= 0 {
+		level, ok := PriorityLevel(event.Priority)
+		if !ok || level > m.maxLevel {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRule - перевіряє ім'я правила за точними іменами та шаблонами
+func (m *Matcher) matchRule(rule string) bool {
+	if m.exact[rule] {
+		return true
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(rule) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package scenario
+
+import (
+	"testing"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+)
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		glob string
+		name string
+		want bool
+	}{
+		{"Terminal*", "Terminal shell in container", true},
+		{"Terminal*", "A Terminal shell", false}, // Шаблон прив'язано до початку імені
+		{"*shell", "Terminal shell in container", false},
+		{"*shell*", "Terminal shell in container", true},
+		{"Rule ?", "Rule A", true},
+		{"Rule ?", "Rule AB", false},
+		{"Rule ?", "Rule ", false},
+		{"Write below /etc*", "Write below /etc/passwd", true},
+		{"a.b*", "a.b.c", true},
+		{"a.b*", "axb", false}, // Крапка - звичайний символ
+		{"(x)+[y]*", "(x)+[y] z", true},
+		{"(x)+[y]*", "xxy", false},
+		{"Привіт?", "Привітт", true}, // ? - одна руна, а не байт
+	}
+	for _, tt := range tests {
+		if got := globRegexp(tt.glob).MatchString(tt.name); got != tt.want {
+			t.Errorf("globRegexp(%q).MatchString(%q) = %v, want %v", tt.glob, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewMatcherErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		falcoRule string
+		match     RuleMatch
+		wantErr   bool
+	}{
+		{name: "falco rule only", falcoRule: "Terminal shell in container"},
+		{name: "glob rule", match: RuleMatch{Rules: []string{"Terminal*"}}},
+		{name: "tags only", match: RuleMatch{Tags: []string{"mitre_execution"}}},
+		{name: "priority only", match: RuleMatch{MinPriority: "Warning"}},
+		{name: "priority case and spaces", match: RuleMatch{MinPriority: " critical "}},
+		{name: "no criteria", wantErr: true},
+		{name: "empty lists", match: RuleMatch{Rules: []string{}, Tags: []string{}}, wantErr: true},
+		{name: "invalid rule_regex", match: RuleMatch{RuleRegex: []string{"shell("}}, wantErr: true},
+		{name: "unknown priority", match: RuleMatch{MinPriority: "severe"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMatcher(tt.falcoRule, tt.match)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewMatcher error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && m == nil {
+				t.Errorf("NewMatcher returned nil matcher without error")
+			}
+		})
+	}
+}
+
+func TestMatcherMatch(t *testing.T) {
+	shell := actioner.Event{RuleName: "Terminal shell in container", Priority: "Notice", Tags: []string{"container", "mitre_execution"}}
+	tests := []struct {
+		name      string
+		falcoRule string
+		match     RuleMatch
+		event     actioner.Event
+		want      bool
+	}{
+		{name: "exact falco rule", falcoRule: "Terminal shell in container", event: shell, want: true},
+		{name: "exact rule is case sensitive", falcoRule: "terminal shell in container", event: shell, want: false},
+		{name: "exact rule not a prefix", match: RuleMatch{Rules: []string{"Terminal shell"}}, event: shell, want: false},
+		{name: "one of rules", match: RuleMatch{Rules: []string{"Other", "Terminal*"}}, event: shell, want: true},
+		{name: "rule_regex is not anchored", match: RuleMatch{RuleRegex: []string{"shell"}}, event: shell, want: true},
+		{name: "anchored rule_regex", match: RuleMatch{RuleRegex: []string{"^shell"}}, event: shell, want: false},
+		{name: "falco rule with rules", falcoRule: "Other", match: RuleMatch{Rules: []string{"Terminal*"}}, event: shell, want: true},
+		{name: "any tag", match: RuleMatch{Tags: []string{"network", "mitre_execution"}}, event: shell, want: true},
+		{name: "missing tag", match: RuleMatch{Tags: []string{"network"}}, event: shell, want: false},
+		{name: "event without tags", match: RuleMatch{Tags: []string{"container"}}, event: actioner.Event{RuleName: shell.RuleName}, want: false},
+		{name: "priority equal", match: RuleMatch{MinPriority: "Notice"}, event: shell, want: true},
+		{name: "priority higher", match: RuleMatch{MinPriority: "Informational"}, event: shell, want: true},
+		{name: "priority lower", match: RuleMatch{MinPriority: "Warning"}, event: shell, want: false},
+		{name: "unknown event priority", match: RuleMatch{MinPriority: "Debug"}, event: actioner.Event{Priority: "urgent"}, want: false},
+		{
+			name:  "all criteria",
+			match: RuleMatch{Rules: []string{"Terminal*"}, Tags: []string{"container"}, MinPriority: "Notice"},
+			event: shell,
+			want:  true,
+		},
+		{
+			name:  "rule matches but tag does not",
+			match: RuleMatch{Rules: []string{"Terminal*"}, Tags: []string{"network"}},
+			event: shell,
+			want:  false,
+		},
+		{
+			name:  "tag matches but rule does not",
+			match: RuleMatch{Rules: []string{"Write below*"}, Tags: []string{"container"}},
+			event: shell,
+			want:  false,
+		},
+		{
+			name:  "tags without rule criteria accept any rule",
+			match: RuleMatch{Tags: []string{"container"}, MinPriority: "Error"},
+			event: actioner.Event{RuleName: "Anything", Priority: "Critical", Tags: []string{"container"}},
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMatcher(tt.falcoRule, tt.match)
+			if err != nil {
+				t.Fatalf("NewMatcher: %v", err)
+			}
+			if got := m.Match(tt.event); got != tt.want {
+				t.Errorf("Match(%+v) = %v, want %v", tt.event, got, tt.want)
+			}
+		})
+	}
+}
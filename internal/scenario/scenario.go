@@ -12,18 +12,24 @@ import (
 type ScenarioConditions struct {
 	TriggerCount int           `mapstructure:"trigger_count"`
 	TimeWindow   time.Duration `mapstructure:"time_window"`
+	SameRule     bool          `mapstructure:"same_rule"` // Рахувати лише події з правилом сценарію
 }
 
-// ShouldTrigger - перевіряє, чи потрібно спрацьовувати діячу
-func ShouldTrigger(conditions ScenarioConditions, event actioner.Event, db *db.Database) bool {
+// ShouldTrigger - перевіряє, чи потрібно спрацьовувати діячу; події рахуються за ключем кореляції key
+func ShouldTrigger(conditions ScenarioConditions, key string, event actioner.Event, db *db.Database) bool {
 	if conditions.TimeWindow == 0 {
 		log.Printf("Warning: TimeWindow is 0, conditions will always fail")
 	}
-	count, err := db.CountEvents(event.IP, conditions.TimeWindow)
+	rule := ""
+	if conditions.SameRule {
+		rule = event.RuleName
+	}
+	value := CorrelationKey(key, event)
+	count, err := db.CountEventsByKey(key, value, rule, conditions.TimeWindow)
 	if err != nil {
-		log.Printf("Error counting events for IP %s: %v", event.IP, err)
+		log.Printf("Error counting events for %s %s: %v", key, value, err)
 		return false
 	}
-	log.Printf("IP %s: %d events in last %d seconds (required: %d)", event.IP, count, conditions.TimeWindow/time.Second, conditions.TriggerCount)
+	log.Printf("%s %s: %d events in last %d seconds (required: %d)", key, value, count, conditions.TimeWindow/time.Second, conditions.TriggerCount)
 	return count >= conditions.TriggerCount
 }
@@ -0,0 +1,115 @@
+package scenario
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+	"github.com/cloudedugcp/responseEngine/internal/db"
+)
+
+// SequenceConfig - сценарій, що спрацьовує на впорядкований ланцюжок правил
+type SequenceConfig struct {
+	Steps  []RuleMatch   `mapstructure:"steps"`  // Кроки по порядку: rules, rule_regex, tags, min_priority
+	Key    string        `mapstructure:"key"`    // Ключ кореляції; замінює key сценарію для всього сценарію (when, conditions, dedup)
+	Within time.Duration `mapstructure:"within"` // Увесь ланцюжок має вкластися в цей час від першого кроку
+}
+
+// Sequence - скомпільований послідовний сценарій; стан зберігається в БД
+type Sequence struct {
+	name   string
+	steps  []*Matcher
+	key    string
+	within time.Duration
+
+	mu sync.Mutex // Серіалізує читання-зміну стану між обробниками черги
+}
+
+// NewSequence - компілює кроки послідовності
+func NewSequence(name string, cfg SequenceConfig) (*Sequence, error) {
+	if len(cfg.Steps) < 2 {
+		return nil, fmt.Errorf("sequence needs at least 2 steps")
+	}
+	if cfg.Within <= 0 {
+		return nil, fmt.Errorf("sequence needs a positive within")
+	}
+	sq := &Sequence{name: name, key: cfg.Key, within: cfg.Within}
+	if sq.key == "" {
+		sq.key = DefaultKey
+	}
+	if !ValidKey(sq.key) {
+		return nil, fmt.Errorf("unknown sequence key %q", cfg.Key)
+	}
+	for i, step := range cfg.Steps {
+		m, err := NewMatcher("", step)
+		if err != nil {
+			return nil, fmt.Errorf("sequence step %d: %v", i+1, err)
+		}
+		sq.steps = append(sq.steps, m)
+	}
+	return sq, nil
+}
+
+// Advance - просуває стан послідовності подією; true, коли пройдено останній крок
+func (sq *Sequence) Advance(event actioner.Event, database *db.Database) bool {
+	key := CorrelationKey(sq.key, event)
+	if key == "" {
+		return false
+	}
+
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	st, found, err := database.GetSequence(sq.name, key)
+	if err != nil {
+		return false
+	}
+	// Час сенсора може йти не по порядку: подія, старша за попередній крок, не відкочує годинник
+	// стану, інакше відʼємна тривалість ніколи б не перевищила within
+	now := event.Time
+	if found && st.Updated.After(now) {
+		now = st.Updated
+	}
+	if found && now.Sub(st.Started) > sq.within {
+		log.Printf("Sequence '%s' for %s=%s expired after step %d", sq.name, sq.key, key, st.Step)
+		database.DeleteSequence(sq.name, key)
+		found = false
+	}
+	if found && (st.Step <= 0 || st.Step >= len(sq.steps)) {
+		// Стан залишився від іншої конфігурації (наприклад, послідовність скоротили) - починаємо заново
+		log.Printf("Sequence '%s' for %s=%s has stale step %d of %d, resetting", sq.name, sq.key, key, st.Step, len(sq.steps))
+		database.DeleteSequence(sq.name, key)
+		found = false
+	}
+	if !found {
+		st = db.SequenceState{}
+	} else if event.Time.Before(st.Started) {
+		// Подія, старша за перший крок, не може бути наступним кроком ланцюжка
+		return false
+	}
+
+	if !sq.steps[st.Step].Match(event) {
+		// Повтор першого кроку починає ланцюжок заново від свіжішої події, щоб вікно within
+		// рахувалося від неї, а не від першої, яка от-от застаріє
+		if st.Step == 0 || !sq.steps[0].Match(event) || !event.Time.After(st.Started) {
+			return false
+		}
+		log.Printf("Sequence '%s' for %s=%s restarted at step 1 (Rule=%s)", sq.name, sq.key, key, event.RuleName)
+		st = db.SequenceState{}
+	}
+	if st.Step == 0 {
+		st.Started = event.Time
+	}
+	st.Step++
+	st.Updated = now
+	if st.Step == len(sq.steps) {
+		log.Printf("Sequence '%s' completed for %s=%s", sq.name, sq.key, key)
+		database.DeleteSequence(sq.name, key)
+		return true
+	}
+	log.Printf("Sequence '%s' for %s=%s at step %d/%d (Rule=%s)", sq.name, sq.key, key, st.Step, len(sq.steps), event.RuleName)
+	database.SaveSequence(sq.name, key, st)
+	return false
+}
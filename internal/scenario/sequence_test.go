@@ -0,0 +1,167 @@
+package scenario
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+	"github.com/cloudedugcp/responseEngine/internal/db"
+)
+
+func newTestDatabase(t *testing.T) *db.Database {
+	t.Helper()
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func steps(rules ...string) []RuleMatch {
+	var out []RuleMatch
+	for _, r := range rules {
+		out = append(out, RuleMatch{Rules: []string{r}})
+	}
+	return out
+}
+
+func TestSequenceAdvance(t *testing.T) {
+	database := newTestDatabase(t)
+	sq, err := NewSequence("chain", SequenceConfig{Steps: steps("a", "b", "c"), Within: time.Minute})
+	if err != nil {
+		t.Fatalf("NewSequence: %v", err)
+	}
+
+	start := time.Now()
+	events := []struct {
+		rule string
+		at   time.Duration
+		want bool
+	}{
+		{"b", 0, false}, // Не перший крок - ігнорується
+		{"a", 0, false},
+		{"x", time.Second, false},
+		{"b", 2 * time.Second, false},
+		{"c", 3 * time.Second, true},
+		{"c", 4 * time.Second, false}, // Після завершення стан скинуто
+	}
+	for i, e := range events {
+		ev := actioner.Event{IP: "10.0.0.1", RuleName: e.rule, Time: start.Add(e.at)}
+		if got := sq.Advance(ev, database); got != e.want {
+			t.Fatalf("event %d (%s): Advance = %v, want %v", i, e.rule, got, e.want)
+		}
+	}
+}
+
+func TestSequenceTimeline(t *testing.T) {
+	type step struct {
+		rule string
+		at   time.Duration
+		want bool
+	}
+	tests := []struct {
+		name   string
+		rules  []string
+		within time.Duration
+		events []step
+	}{
+		{
+			name:   "repeated first step restarts window",
+			rules:  []string{"a", "b"},
+			within: 30 * time.Minute,
+			events: []step{{"a", 0, false}, {"a", 25 * time.Minute, false}, {"b", 35 * time.Minute, true}},
+		},
+		{
+			name:   "first step not repeated expires",
+			rules:  []string{"a", "b"},
+			within: 30 * time.Minute,
+			events: []step{{"a", 0, false}, {"b", 35 * time.Minute, false}},
+		},
+		{
+			name:   "older repeat of first step does not restart",
+			rules:  []string{"a", "b"},
+			within: 30 * time.Minute,
+			events: []step{{"a", 10 * time.Minute, false}, {"a", 0, false}, {"b", 39 * time.Minute, true}},
+		},
+		{
+			name:   "step older than chain start is ignored",
+			rules:  []string{"a", "b"},
+			within: time.Minute,
+			events: []step{{"a", 0, false}, {"b", -10 * time.Second, false}, {"b", 10 * time.Second, true}},
+		},
+		{
+			name:   "out of order step after chain start",
+			rules:  []string{"a", "b", "c"},
+			within: time.Minute,
+			events: []step{{"a", 0, false}, {"b", 50 * time.Second, false}, {"x", 55 * time.Second, false}, {"c", 20 * time.Second, true}},
+		},
+		{
+			name:   "backdated event does not keep expired state",
+			rules:  []string{"a", "b", "c"},
+			within: time.Minute,
+			events: []step{{"a", 0, false}, {"b", 30 * time.Second, false}, {"c", 2 * time.Minute, false}, {"c", 10 * time.Second, false}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := newTestDatabase(t)
+			sq, err := NewSequence("chain", SequenceConfig{Steps: steps(tt.rules...), Within: tt.within})
+			if err != nil {
+				t.Fatalf("NewSequence: %v", err)
+			}
+			start := time.Now()
+			for i, e := range tt.events {
+				ev := actioner.Event{IP: "10.0.0.1", RuleName: e.rule, Time: start.Add(e.at)}
+				if got := sq.Advance(ev, database); got != e.want {
+					t.Fatalf("event %d (%s at %s): Advance = %v, want %v", i, e.rule, e.at, got, e.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSequenceExpires(t *testing.T) {
+	database := newTestDatabase(t)
+	sq, err := NewSequence("chain", SequenceConfig{Steps: steps("a", "b"), Within: time.Minute})
+	if err != nil {
+		t.Fatalf("NewSequence: %v", err)
+	}
+
+	start := time.Now()
+	sq.Advance(actioner.Event{IP: "10.0.0.1", RuleName: "a", Time: start}, database)
+	if sq.Advance(actioner.Event{IP: "10.0.0.1", RuleName: "b", Time: start.Add(2 * time.Minute)}, database) {
+		t.Fatalf("sequence completed after within elapsed")
+	}
+}
+
+func TestSequenceRestartWithShorterSteps(t *testing.T) {
+	database := newTestDatabase(t)
+	start := time.Now()
+
+	// Стан, збережений попередньою, довшою конфігурацією послідовності
+	for _, step := range []int{2, 3, 7} {
+		st := db.SequenceState{Step: step, Started: start, Updated: start}
+		if err := database.SaveSequence("chain", "10.0.0.1", st); err != nil {
+			t.Fatalf("SaveSequence: %v", err)
+		}
+
+		sq, err := NewSequence("chain", SequenceConfig{Steps: steps("a", "b"), Within: time.Minute})
+		if err != nil {
+			t.Fatalf("NewSequence: %v", err)
+		}
+		if sq.Advance(actioner.Event{IP: "10.0.0.1", RuleName: "b", Time: start.Add(time.Second)}, database) {
+			t.Fatalf("step %d: stale state completed the sequence", step)
+		}
+		if _, found, _ := database.GetSequence("chain", "10.0.0.1"); found {
+			t.Fatalf("step %d: stale state was not reset", step)
+		}
+		if sq.Advance(actioner.Event{IP: "10.0.0.1", RuleName: "a", Time: start.Add(2 * time.Second)}, database) {
+			t.Fatalf("step %d: first step completed the sequence", step)
+		}
+		if !sq.Advance(actioner.Event{IP: "10.0.0.1", RuleName: "b", Time: start.Add(3 * time.Second)}, database) {
+			t.Fatalf("step %d: sequence did not complete after restart", step)
+		}
+	}
+}
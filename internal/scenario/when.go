@@ -0,0 +1,220 @@
+package scenario
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/parser"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+	"github.com/cloudedugcp/responseEngine/internal/db"
+)
+
+// historyType - тип змінної history, через яку вирази звертаються до журналу подій
+var historyType = cel.OpaqueType("history")
+
+// history - журнал подій за ключем кореляції поточної події
+type history struct {
+	key   string
+	value string
+	db    *db.Database
+}
+
+func (h history) ConvertToNative(typeDesc reflect.Type) (any, error) {
+	return nil, fmt.Errorf("history cannot be converted to %v", typeDesc)
+}
+
+func (h history) ConvertToType(typeVal ref.Type) ref.Val {
+	return types.NewErr("history cannot be converted to %v", typeVal)
+}
+
+func (h history) Equal(other ref.Val) ref.Val {
+	o, ok := other.(history)
+	return types.Bool(ok && o.key == h.key && o.value == h.value)
+}
+
+func (h history) Type() ref.Type { return historyType }
+
+func (h history) Value() any { return h }
+
+// When - скомпільований CEL-вираз умови сценарію (поле when)
+type When struct {
+	expr string
+	prg  cel.Program
+}
+
+// whenEnv - середовище CEL: поля події, константи пріоритетів, ip.inCidr() та агрегати журналу
+func whenEnv() (*cel.Env, error) {
+	opts := []cel.EnvOption{
+		cel.Variable("ip", cel.StringType),
+		cel.Variable("rule", cel.StringType),
+		cel.Variable("priority", cel.IntType), // Debug = 0 ... Emergency = 7
+		cel.Variable("priority_name", cel.StringType),
+		cel.Variable("source", cel.StringType),
+		cel.Variable("hostname", cel.StringType),
+		cel.Variable("log", cel.StringType),
+		cel.Variable("tags", cel.ListType(cel.StringType)),
+		cel.Variable("fields", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("time", cel.TimestampType),
+		cel.Variable("key", cel.StringType), // Значення ключа кореляції сценарію
+		cel.Variable("history", historyType),
+
+		cel.Function("inCidr", cel.MemberOverload("string_in_cidr_string",
+			[]*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+			cel.BinaryBinding(inCidr))),
+		cel.Function("event_count", windowOverloads("event_count", func(h history, window time.Duration) (int, error) {
+			return h.db.CountEventsByKey(h.key, h.value, "", window)
+		})...),
+		cel.Function("distinct_rules", windowOverloads("distinct_rules", func(h history, window time.Duration) (int, error) {
+			return h.db.CountDistinctRules(h.key, h.value, window)
+		})...),
+		cel.Function("block_count", cel.MemberOverload("history_block_count",
+			[]*cel.Type{historyType}, cel.IntType,
+			cel.UnaryBinding(func(v ref.Val) ref.Val {
+				h := v.(history)
+				count, err := h.db.GetBlockCount(db.Subject(h.key, h.value))
+				if err != nil {
+					return types.NewErr("block_count: %v", err)
+				}
+				return types.Int(count)
+			}))),
+
+		// distinct_rules(1h) тощо - скорочення для history.distinct_rules(...)
+		cel.Macros(
+			cel.GlobalMacro("event_count", 1, historyMacro("event_count")),
+			cel.GlobalMacro("distinct_rules", 1, historyMacro("distinct_rules")),
+			cel.GlobalMacro("block_count", 0, historyMacro("block_count")),
+		),
+	}
+	for name, level := range priorityLevels {
+		if name == "info" {
+			continue
+		}
+		opts = append(opts, cel.Constant(priorityConstant(name), cel.IntType, types.Int(rank(level))))
+	}
+	return cel.NewEnv(opts...)
+}
+
+// priorityConstant - ім'я константи пріоритету у виразах (Critical, Warning, ...)
+func priorityConstant(name string) string {
+	return string(name[0]-'a'+'A') + name[1:]
+}
+
+// rank - ранг пріоритету для порівнянь у виразах: чим серйозніше, тим більше
+func rank(level int) int {
+	return 7 - level
+}
+
+// historyMacro - розгортає глобальний виклик у виклик методу змінної history
+func historyMacro(function string) cel.MacroFactory {
+	return func(eh parser.ExprHelper, _ ast.Expr, args []ast.Expr) (ast.Expr, *common.Error) {
+		return eh.NewMemberCall(function, eh.NewIdent("history"), args...), nil
+	}
+}
+
+// windowOverloads - перевантаження агрегату з вікном як duration('1h') або рядком '1h'
+func windowOverloads(function string, count func(h history, window time.Duration) (int, error)) []cel.FunctionOpt {
+	binding := func(lhs, rhs ref.Val) ref.Val {
+		var window time.Duration
+		switch w := rhs.(type) {
+		case types.Duration:
+			window = w.Duration
+		case types.String:
+			d, err := time.ParseDuration(string(w))
+			if err != nil {
+				return types.NewErr("%s: invalid window %q", function, string(w))
+			}
+			window = d
+		default:
+			return types.MaybeNoSuchOverloadErr(rhs)
+		}
+		n, err := count(lhs.(history), window)
+		if err != nil {
+			return types.NewErr("%s: %v", function, err)
+		}
+		return types.Int(n)
+	}
+	return []cel.FunctionOpt{
+		cel.MemberOverload("history_"+function+"_duration", []*cel.Type{historyType, cel.DurationType}, cel.IntType, cel.BinaryBinding(binding)),
+		cel.MemberOverload("history_"+function+"_string", []*cel.Type{historyType, cel.StringType}, cel.IntType, cel.BinaryBinding(binding)),
+	}
+}
+
+// inCidr - перевіряє, чи належить IP до мережі; некоректний IP не належить жодній мережі
+func inCidr(ipVal, cidrVal ref.Val) ref.Val {
+	prefix, err := netip.ParsePrefix(string(cidrVal.(types.String)))
+	if err != nil {
+		return types.NewErr("inCidr: invalid CIDR %q", string(cidrVal.(types.String)))
+	}
+	addr, err := netip.ParseAddr(string(ipVal.(types.String)))
+	if err != nil {
+		return types.False
+	}
+	return types.Bool(prefix.Contains(addr.Unmap()))
+}
+
+// CompileWhen - компілює вираз умови; результат має бути bool
+func CompileWhen(expr string) (*When, error) {
+	env, err := whenEnv()
+	if err != nil {
+		return nil, err
+	}
+	checked, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("invalid when expression: %v", iss.Err())
+	}
+	if checked.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("when expression must return bool, got %v", checked.OutputType())
+	}
+	prg, err := env.Program(checked)
+	if err != nil {
+		return nil, err
+	}
+	return &When{expr: expr, prg: prg}, nil
+}
+
+// Eval - обчислює вираз для події; агрегати рахуються за ключем кореляції key
+func (w *When) Eval(event actioner.Event, key string, database *db.Database) (bool, error) {
+	priority := 0
+	if level, ok := PriorityLevel(event.Priority); ok {
+		priority = rank(level)
+	}
+	tags := event.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	fields := event.Fields
+	if fields == nil {
+		fields = map[string]string{}
+	}
+
+	out, _, err := w.prg.Eval(map[string]any{
+		"ip":            event.IP,
+		"rule":          event.RuleName,
+		"priority":      priority,
+		"priority_name": event.Priority,
+		"source":        event.Source,
+		"hostname":      event.Hostname,
+		"log":           event.Log,
+		"tags":          tags,
+		"fields":        fields,
+		"time":          event.Time,
+		"key":           CorrelationKey(key, event),
+		"history":       history{key: key, value: CorrelationKey(key, event), db: database},
+	})
+	if err != nil {
+		return false, fmt.Errorf("when %q: %v", w.expr, err)
+	}
+	result, ok := out.(types.Bool)
+	if !ok {
+		return false, fmt.Errorf("when %q returned %v", w.expr, out)
+	}
+	return bool(result), nil
+}
@@ -0,0 +1,92 @@
+package scenario
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+	"github.com/cloudedugcp/responseEngine/internal/db"
+)
+
+func TestCompileWhen(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{expr: "priority >= Critical"},
+		{expr: "ip.inCidr('10.0.0.0/8') && event_count('1h') > 2"},
+		{expr: "distinct_rules(duration('30m')) >= 2 || block_count() > 0"},
+		{expr: "rule", wantErr: true},         // Не bool
+		{expr: "priority >=", wantErr: true},  // Синтаксична помилка
+		{expr: "unknown == 1", wantErr: true}, // Невідома змінна
+		{expr: "Info > 1", wantErr: true},     // Скорочення info - не константа, лише Informational
+	}
+	for _, tt := range tests {
+		_, err := CompileWhen(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CompileWhen(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWhenEval(t *testing.T) {
+	database := newTestDatabase(t)
+	now := time.Now()
+	for i, rule := range []string{"a", "b", "b"} {
+		_, err := database.RecordEvent(db.EventRecord{IP: "203.0.113.7", Rule: rule, Source: "falco", Timestamp: now.Add(-time.Duration(i) * time.Minute)})
+		if err != nil {
+			t.Fatalf("RecordEvent: %v", err)
+		}
+	}
+	// Подія поза вікном 1h не рахується
+	database.RecordEvent(db.EventRecord{IP: "203.0.113.7", Rule: "c", Source: "falco", Timestamp: now.Add(-2 * time.Hour)})
+	database.RecordEvent(db.EventRecord{Rule: "a", Source: "falco", Timestamp: now, Keys: map[string]string{"pod": "ns/web"}})
+	database.LogAction("203.0.113.7", "block", "blocked", now)
+
+	event := actioner.Event{IP: "203.0.113.7", RuleName: "b", Priority: "Critical", Time: now}
+	podEvent := actioner.Event{RuleName: "a", Priority: "Notice", Fields: map[string]string{"k8s.ns.name": "ns", "k8s.pod.name": "web"}}
+	tests := []struct {
+		name    string
+		expr    string
+		event   actioner.Event
+		key     string
+		want    bool
+		wantErr bool
+	}{
+		{name: "priority at constant", expr: "priority >= Critical", event: event, want: true},
+		{name: "priority below constant", expr: "priority >= Alert", event: event, want: false},
+		{name: "priority ordering", expr: "Emergency > Warning && Warning > Debug", event: event, want: true},
+		{name: "unknown priority ranks lowest", expr: "priority == Debug", event: actioner.Event{Priority: "bogus"}, want: true},
+		{name: "in cidr", expr: "ip.inCidr('203.0.113.0/24')", event: event, want: true},
+		{name: "not in cidr", expr: "ip.inCidr('10.0.0.0/8')", event: event, want: false},
+		{name: "mapped ipv6", expr: "ip.inCidr('203.0.113.0/24')", event: actioner.Event{IP: "::ffff:203.0.113.7"}, want: true},
+		{name: "invalid ip", expr: "ip.inCidr('10.0.0.0/8')", event: actioner.Event{IP: "not-an-ip"}, want: false},
+		{name: "invalid cidr", expr: "ip.inCidr('10.0.0.0/33')", event: event, wantErr: true},
+		{name: "event count string window", expr: "event_count('1h') == 3", event: event, want: true},
+		{name: "event count duration window", expr: "event_count(duration('90s')) == 2", event: event, want: true},
+		{name: "event count invalid window", expr: "event_count('soon') > 0", event: event, wantErr: true},
+		{name: "distinct rules", expr: "distinct_rules('1h') == 2", event: event, want: true},
+		{name: "block count", expr: "block_count() == 1", event: event, want: true},
+		{name: "count by pod key", expr: "key == 'ns/web' && event_count('1h') == 1 && block_count() == 0", event: podEvent, key: "pod", want: true},
+		{name: "fields and tags", expr: "fields['k8s.pod.name'] == 'web' && size(tags) == 0", event: podEvent, key: "pod", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := CompileWhen(tt.expr)
+			if err != nil {
+				t.Fatalf("CompileWhen: %v", err)
+			}
+			key := tt.key
+			if key == "" {
+				key = DefaultKey
+			}
+			got, err := w.Eval(tt.event, key, database)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Eval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/config"
+)
+
+const (
+	defaultSignatureHeader = "X-Signature"
+	defaultTimestampHeader = "X-Timestamp"
+	defaultReplayWindow    = 5 * time.Minute
+	replayPruneSize        = 10000 // Після стількох запам'ятованих підписів очищення не чекає на таймер
+)
+
+// authenticator - перевіряє автентичність запитів до аліасу
+type authenticator struct {
+	token           string
+	hmacSecret      []byte
+	signatureHeader string
+	timestampHeader string
+	replayWindow    time.Duration
+	pins            map[string]bool
+	misconfigured   error // Блок auth задано, але жоден метод не придатний - відхиляємо всі запити
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // Вже використані підписи (захист від повторів)
+	lastPrune time.Time
+}
+
+// newAuthenticator - створює перевірку за налаштуваннями джерела; nil, якщо блоку auth немає.
+// Якщо секрет задано, але він порожній після підстановки змінних середовища, перевірка відхиляє всі запити
+func newAuthenticator(source string, cfg *config.AuthConfig) *authenticator {
+	if cfg == nil {
+		return nil
+	}
+	a := &authenticator{
+		token:           os.ExpandEnv(cfg.Token),
+		signatureHeader: cfg.SignatureHeader,
+		timestampHeader: cfg.TimestampHeader,
+		replayWindow:    cfg.ReplayWindow,
+		seen:            make(map[string]time.Time),
+	}
+	if secret := os.ExpandEnv(cfg.HMACSecret); secret != "" {
+		a.hmacSecret = []byte(secret)
+	}
+	if len(cfg.ClientCertSHA256) > 0 {
+		a.pins = make(map[string]bool, len(cfg.ClientCertSHA256))
+		for _, pin := range cfg.ClientCertSHA256 {
+			a.pins[normalizeFingerprint(pin)] = true
+		}
+	}
+	switch {
+	case cfg.Token != "" && a.token == "":
+		a.misconfigured = fmt.Errorf("auth token %q expands to an empty value", cfg.Token)
+	case cfg.HMACSecret != "" && a.hmacSecret == nil:
+		a.misconfigured = fmt.Errorf("hmac_secret %q expands to an empty value", cfg.HMACSecret)
+	case a.token == "" && a.hmacSecret == nil && a.pins == nil:
+		a.misconfigured = fmt.Errorf("auth block has no token, hmac_secret or client_cert_sha256")
+	}
+	if a.misconfigured != nil {
+		log.Printf("Source %s: %v, rejecting all requests", source, a.misconfigured)
+	}
+	if a.signatureHeader == "" {
+		a.signatureHeader = defaultSignatureHeader
+	}
+	if a.timestampHeader == "" {
+		a.timestampHeader = defaultTimestampHeader
+	}
+	if a.replayWindow <= 0 {
+		a.replayWindow = defaultReplayWindow
+	}
+	return a
+}
+
+// check - перевіряє всі налаштовані методи; повертає причину відмови ("token", "signature", ...) і помилку
+func (a *authenticator) check(r *http.Request, body []byte) (string, error) {
+	if a.misconfigured != nil {
+		return "misconfigured", a.misconfigured
+	}
+	if a.pins != nil {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return "client_cert", fmt.Errorf("client certificate required")
+		}
+		sum := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
+		if !a.pins[hex.EncodeToString(sum[:])] {
+			return "client_cert", fmt.Errorf("client certificate is not pinned")
+		}
+	}
+
+	if a.token != "" {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(a.token)) != 1 {
+			return "token", fmt.Errorf("invalid bearer token")
+		}
+	}
+
+	if a.hmacSecret != nil {
+		ts := r.Header.Get(a.timestampHeader)
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return "timestamp", fmt.Errorf("missing or invalid %s header", a.timestampHeader)
+		}
+		now := time.Now()
+		sent := time.Unix(sec, 0)
+		if now.Sub(sent) > a.replayWindow || sent.Sub(now) > a.replayWindow {
+			return "timestamp", fmt.Errorf("timestamp outside of %s replay window", a.replayWindow)
+		}
+
+		sig := strings.TrimPrefix(r.Header.Get(a.signatureHeader), "sha256=")
+		got, err := hex.DecodeString(sig)
+		if err != nil || len(got) == 0 {
+			return "signature", fmt.Errorf("missing or invalid %s header", a.signatureHeader)
+		}
+		mac := hmac.New(sha256.New, a.hmacSecret)
+		mac.Write([]byte(ts))
+		mac.Write([]byte("."))
+		mac.Write(body)
+		if !hmac.Equal(got, mac.Sum(nil)) {
+			return "signature", fmt.Errorf("signature mismatch")
+		}
+		if !a.remember(hex.EncodeToString(got), now) {
+			return "replay", fmt.Errorf("signature already used")
+		}
+	}
+	return "", nil
+}
+
+// remember - запам'ятовує підпис; false, якщо його вже бачили у вікні повторів
+func (a *authenticator) remember(sig string, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if now.Sub(a.lastPrune) > a.replayWindow || len(a.seen) > replayPruneSize {
+		for s, t := range a.seen {
+			if now.Sub(t) > 2*a.replayWindow {
+				delete(a.seen, s)
+			}
+		}
+		a.lastPrune = now
+	}
+	if _, ok := a.seen[sig]; ok {
+		return false
+	}
+	a.seen[sig] = now
+	return true
+}
+
+// normalizeFingerprint - приводить відбиток SHA-256 до нижнього регістру без двокрапок
+func normalizeFingerprint(fp string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fp), ":", ""))
+}
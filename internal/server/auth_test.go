@@ -0,0 +1,281 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+	"github.com/cloudedugcp/responseEngine/internal/config"
+	"github.com/cloudedugcp/responseEngine/internal/db"
+	"github.com/cloudedugcp/responseEngine/internal/decoder"
+)
+
+// sign - підпис "<timestamp>.<body>" ключем secret у hex
+func sign(secret, ts, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "." + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// fingerprint - відбиток SHA-256 сертифіката у форматі з двокрапками, як у конфігурації
+func fingerprint(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	var parts []string
+	for _, b := range sum {
+		parts = append(parts, strings.ToUpper(hex.EncodeToString([]byte{b})))
+	}
+	return strings.Join(parts, ":")
+}
+
+func TestAuthenticatorCheck(t *testing.T) {
+	const body = `{"rule":"r"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	skewed := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	pinned := []byte("pinned certificate")
+
+	tests := []struct {
+		name    string
+		cfg     config.AuthConfig
+		env     map[string]string
+		headers map[string]string
+		certs   [][]byte // nil - запит без TLS
+		reason  string   // Порожня - запит має пройти
+	}{
+		{
+			name:    "valid token",
+			cfg:     config.AuthConfig{Token: "secret"},
+			headers: map[string]string{"Authorization": "Bearer secret"},
+		},
+		{
+			name:    "token without Bearer scheme",
+			cfg:     config.AuthConfig{Token: "secret"},
+			headers: map[string]string{"Authorization": "secret"},
+			reason:  "token",
+		},
+		{
+			name:    "wrong token",
+			cfg:     config.AuthConfig{Token: "secret"},
+			headers: map[string]string{"Authorization": "Bearer other"},
+			reason:  "token",
+		},
+		{
+			name:    "token from environment",
+			cfg:     config.AuthConfig{Token: "${AUTH_TEST_TOKEN}"},
+			env:     map[string]string{"AUTH_TEST_TOKEN": "from-env"},
+			headers: map[string]string{"Authorization": "Bearer from-env"},
+		},
+		{
+			name:    "token expands to empty",
+			cfg:     config.AuthConfig{Token: "${AUTH_TEST_TOKEN}"},
+			env:     map[string]string{"AUTH_TEST_TOKEN": ""},
+			headers: map[string]string{"Authorization": "Bearer "},
+			reason:  "misconfigured",
+		},
+		{
+			name:    "hmac secret expands to empty",
+			cfg:     config.AuthConfig{HMACSecret: "${AUTH_TEST_SECRET}"},
+			env:     map[string]string{"AUTH_TEST_SECRET": ""},
+			headers: map[string]string{"X-Timestamp": now, "X-Signature": sign("", now, body)},
+			reason:  "misconfigured",
+		},
+		{
+			name:    "valid signature",
+			cfg:     config.AuthConfig{HMACSecret: "key"},
+			headers: map[string]string{"X-Timestamp": now, "X-Signature": sign("key", now, body)},
+		},
+		{
+			name:    "signature with sha256 prefix",
+			cfg:     config.AuthConfig{HMACSecret: "key"},
+			headers: map[string]string{"X-Timestamp": now, "X-Signature": "sha256=" + sign("key", now, body)},
+		},
+		{
+			name:    "signature with wrong key",
+			cfg:     config.AuthConfig{HMACSecret: "key"},
+			headers: map[string]string{"X-Timestamp": now, "X-Signature": sign("other", now, body)},
+			reason:  "signature",
+		},
+		{
+			name:    "signature not hex",
+			cfg:     config.AuthConfig{HMACSecret: "key"},
+			headers: map[string]string{"X-Timestamp": now, "X-Signature": "not-hex"},
+			reason:  "signature",
+		},
+		{
+			name:    "missing timestamp",
+			cfg:     config.AuthConfig{HMACSecret: "key"},
+			headers: map[string]string{"X-Signature": sign("key", now, body)},
+			reason:  "timestamp",
+		},
+		{
+			name:    "skewed timestamp",
+			cfg:     config.AuthConfig{HMACSecret: "key"},
+			headers: map[string]string{"X-Timestamp": skewed, "X-Signature": sign("key", skewed, body)},
+			reason:  "timestamp",
+		},
+		{
+			name:    "custom headers",
+			cfg:     config.AuthConfig{HMACSecret: "key", SignatureHeader: "X-Hub-Signature-256", TimestampHeader: "X-Hub-Time"},
+			headers: map[string]string{"X-Hub-Time": now, "X-Hub-Signature-256": "sha256=" + sign("key", now, body)},
+		},
+		{
+			name:  "pinned certificate",
+			cfg:   config.AuthConfig{ClientCertSHA256: []string{fingerprint(pinned)}},
+			certs: [][]byte{pinned},
+		},
+		{
+			name:   "unpinned certificate",
+			cfg:    config.AuthConfig{ClientCertSHA256: []string{fingerprint(pinned)}},
+			certs:  [][]byte{[]byte("other certificate")},
+			reason: "client_cert",
+		},
+		{
+			name:   "pin without TLS",
+			cfg:    config.AuthConfig{ClientCertSHA256: []string{fingerprint(pinned)}},
+			reason: "client_cert",
+		},
+		{
+			name:   "empty auth block",
+			cfg:    config.AuthConfig{},
+			reason: "misconfigured",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			a := newAuthenticator("test", &tt.cfg)
+			r := httptest.NewRequest(http.MethodPost, "/falco", strings.NewReader(body))
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if tt.certs != nil {
+				r.TLS = &tls.ConnectionState{}
+				for _, raw := range tt.certs {
+					r.TLS.PeerCertificates = append(r.TLS.PeerCertificates, &x509.Certificate{Raw: raw})
+				}
+			}
+			reason, err := a.check(r, []byte(body))
+			if reason != tt.reason {
+				t.Fatalf("check = %q (%v), want %q", reason, err, tt.reason)
+			}
+			if (err == nil) != (tt.reason == "") {
+				t.Fatalf("check error = %v with reason %q", err, reason)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorReplay(t *testing.T) {
+	const body = `{"rule":"r"}`
+	a := newAuthenticator("test", &config.AuthConfig{HMACSecret: "key"})
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	request := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/falco", strings.NewReader(body))
+		r.Header.Set("X-Timestamp", ts)
+		r.Header.Set("X-Signature", sign("key", ts, body))
+		return r
+	}
+	if reason, err := a.check(request(), []byte(body)); err != nil {
+		t.Fatalf("first request rejected: %s: %v", reason, err)
+	}
+	if reason, _ := a.check(request(), []byte(body)); reason != "replay" {
+		t.Fatalf("replayed request: reason = %q, want replay", reason)
+	}
+}
+
+func TestAuthenticatorRemember(t *testing.T) {
+	a := newAuthenticator("test", &config.AuthConfig{HMACSecret: "key", ReplayWindow: time.Minute})
+	start := time.Now()
+	tests := []struct {
+		name string
+		sig  string
+		at   time.Duration
+		want bool
+	}{
+		{name: "first use", sig: "a", want: true},
+		{name: "replay in window", sig: "a", at: 30 * time.Second, want: false},
+		{name: "other signature", sig: "b", at: 30 * time.Second, want: true},
+		{name: "replay after window", sig: "a", at: 90 * time.Second, want: false}, // Підпис пам'ятається 2 вікна
+		{name: "forgotten after two windows", sig: "a", at: 3 * time.Minute, want: true},
+	}
+	for _, tt := range tests {
+		if got := a.remember(tt.sig, start.Add(tt.at)); got != tt.want {
+			t.Errorf("%s: remember(%q) = %v, want %v", tt.name, tt.sig, got, tt.want)
+		}
+	}
+}
+
+func TestAuthRoutedSource(t *testing.T) {
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer database.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Aliases: map[string]string{"/falco": "falco_events", "/cilium": "cilium_events", "/pubsub": "pubsub_events"},
+			Sources: map[string]config.SourceConfig{
+				"falco_events":  {Auth: &config.AuthConfig{Token: "secret"}},
+				"pubsub_events": {PubSub: config.PubSubConfig{Enabled: true, DecoderAttribute: "source"}},
+			},
+			CloudEvents: config.CloudEventsConfig{Routes: []config.CloudEventsRoute{{Type: "falco.*", To: "falco_events"}}},
+		},
+		Scenarios: []config.Scenario{{
+			Name:      "block",
+			FalcoRule: "Suspicious",
+			Source:    "falco_events",
+			Actioners: []config.ScenarioActioner{{Name: "store"}},
+		}},
+	}
+	store := &stubActioner{name: "store"}
+	decoders := map[string]decoder.Decoder{
+		"falco_events":  decoder.NewFalcoDecoder(nil),
+		"cilium_events": decoder.NewFalcoDecoder(nil),
+		"pubsub_events": decoder.NewFalcoDecoder(nil),
+	}
+	s := NewServer(cfg, database, map[string]actioner.Actioner{"store": store}, decoders)
+
+	const alert = `{"ip":"203.0.113.7","rule":"Suspicious"}`
+	pubsub := `{"message":{"data":"eyJpcCI6IjIwMy4wLjExMy43IiwicnVsZSI6IlN1c3BpY2lvdXMifQ==","messageId":"1","attributes":{"source":"falco_events"}}}`
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		headers map[string]string
+		want    int
+	}{
+		{name: "cloudevent routed without token", path: "/cilium", body: alert, headers: map[string]string{"Ce-Specversion": "1.0", "Ce-Type": "falco.alert"}, want: http.StatusUnauthorized},
+		{name: "cloudevent routed with token", path: "/cilium", body: alert, headers: map[string]string{"Ce-Specversion": "1.0", "Ce-Type": "falco.alert", "Authorization": "Bearer secret"}, want: http.StatusOK},
+		{name: "pubsub routed without token", path: "/pubsub", body: pubsub, want: http.StatusUnauthorized},
+		{name: "pubsub routed with token", path: "/pubsub", body: pubsub, headers: map[string]string{"Authorization": "Bearer secret"}, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.calls = 0
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			s.eventHandler(rec, r)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want == http.StatusUnauthorized && store.calls != 0 {
+				t.Errorf("rejected request ran the actioner")
+			}
+		})
+	}
+}
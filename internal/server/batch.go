@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+)
+
+// itemResult - результат обробки одного елемента пакета
+type itemResult struct {
+	Index      int      `json:"index"`
+	Status     string   `json:"status"`           // accepted або rejected
+	Reason     string   `json:"reason,omitempty"` // Причина відхилення
+	Error      string   `json:"error,omitempty"`  // Помилка виконання діячів
+	Events     int      `json:"events"`
+	Suppressed int      `json:"suppressed,omitempty"` // Події-повтори, відкинуті дедуплікацією
+	Throttled  int      `json:"throttled,omitempty"`  // Події понад ліміт швидкості (відкинуті, відкладені або агреговані)
+	Scenarios  []string `json:"scenarios,omitempty"`
+}
+
+// batchResult - підсумок обробки запиту
+type batchResult struct {
+	Accepted   int          `json:"accepted"`
+	Rejected   int          `json:"rejected"`
+	Failed     int          `json:"failed"`           // Прийняті елементи, для яких не виконався хоча б один діяч
+	Queued     bool         `json:"queued,omitempty"` // Події поставлені в чергу, сценарії виконуються асинхронно
+	Suppressed int          `json:"suppressed,omitempty"`
+	Throttled  int          `json:"throttled,omitempty"`
+	Items      []itemResult `json:"items"`
+
+	queueFull bool // Черга переповнена, події не прийнято
+	tooLarge  bool // Подій у пакеті більше, ніж місткість черги
+}
+
+// add - додає результат елемента до підсумку
+func (br *batchResult) add(item itemResult) {
+	if item.Status == "accepted" {
+		br.Accepted++
+	} else {
+		br.Rejected++
+	}
+	if item.Error != "" {
+		br.Failed++
+	}
+	br.Suppressed += item.Suppressed
+	br.Throttled += item.Throttled
+	br.Items = append(br.Items, item)
+}
+
+// rejectAll - відхиляє всі прийняті елементи із зазначеною причиною
+func (br *batchResult) rejectAll(reason string) {
+	for i := range br.Items {
+		if br.Items[i].Status == "accepted" {
+			br.Items[i].Status = "rejected"
+			br.Items[i].Reason = reason
+		}
+	}
+	br.Rejected += br.Accepted
+	br.Accepted = 0
+}
+
+// merge - додає результати іншого підсумку, продовжуючи нумерацію елементів
+func (br *batchResult) merge(other batchResult) {
+	br.Queued = br.Queued || other.Queued
+	br.queueFull = br.queueFull || other.queueFull
+	br.tooLarge = br.tooLarge || other.tooLarge
+	offset := len(br.Items)
+	for _, item := range other.Items {
+		item.Index += offset
+		br.add(item)
+	}
+}
+
+// splitBatch - розбиває тіло запиту на окремі документи:
+// JSON-масив - на елементи, NDJSON - на рядки, один об'єкт лишається як є
+func splitBatch(body []byte) [][]byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil
+	}
+
+	if json.Valid(trimmed) {
+		if trimmed[0] != '[' {
+			return [][]byte{trimmed}
+		}
+		var elements []json.RawMessage
+		if err := json.Unmarshal(trimmed, &elements); err == nil {
+			items := make([][]byte, 0, len(elements))
+			for _, el := range elements {
+				items = append(items, el)
+			}
+			return items
+		}
+	}
+
+	var items [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		items = append(items, append([]byte(nil), line...))
+	}
+	if scanner.Err() != nil {
+		// Рядок задовгий - віддаємо тіло цілком, декодер поверне помилку
+		return [][]byte{trimmed}
+	}
+	return items
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitBatch(t *testing.T) {
+	long := `{"log":"` + strings.Repeat("a", 16*1024*1024) + `"}`
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "empty", input: ""},
+		{name: "whitespace", input: " \n\t\n"},
+		{name: "single object", input: ` {"rule":"a"} ` + "\n", want: []string{`{"rule":"a"}`}},
+		{name: "pretty printed object", input: "{\n  \"rule\": \"a\"\n}", want: []string{"{\n  \"rule\": \"a\"\n}"}},
+		{name: "array", input: `[{"rule":"a"}, {"rule":"b"}]`, want: []string{`{"rule":"a"}`, `{"rule":"b"}`}},
+		{name: "empty array", input: `[]`, want: []string{}},
+		{name: "ndjson", input: "{\"rule\":\"a\"}\n\n{\"rule\":\"b\"}\r\n", want: []string{`{"rule":"a"}`, `{"rule":"b"}`}},
+		{name: "ndjson of arrays", input: "[1]\n[2]", want: []string{"[1]", "[2]"}},
+		{name: "invalid line kept for decoder", input: "{\"rule\":\"a\"}\nnot json", want: []string{`{"rule":"a"}`, "not json"}},
+		{name: "over-long line", input: "{\"rule\":\"a\"}\n" + long, want: []string{"{\"rule\":\"a\"}\n" + long}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitBatch([]byte(tt.input))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d items, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if string(got[i]) != tt.want[i] {
+					t.Errorf("item %d = %.80q, want %.80q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBatchResultMerge(t *testing.T) {
+	var result batchResult
+	first := batchResult{}
+	first.add(itemResult{Index: 0, Status: "accepted"})
+	first.add(itemResult{Index: 1, Status: "rejected", Reason: "bad"})
+	second := batchResult{Queued: true}
+	second.add(itemResult{Index: 0, Status: "accepted", Error: "actioners failed: x", Suppressed: 2})
+
+	result.merge(first)
+	result.merge(second)
+	if result.Accepted != 2 || result.Rejected != 1 || result.Failed != 1 || result.Suppressed != 2 || !result.Queued {
+		t.Fatalf("merged result = %+v", result)
+	}
+	for i, item := range result.Items {
+		if item.Index != i {
+			t.Errorf("item %d has index %d", i, item.Index)
+		}
+	}
+
+	result.rejectAll("queue full")
+	if result.Accepted != 0 || result.Rejected != 3 {
+		t.Fatalf("after rejectAll: accepted %d, rejected %d", result.Accepted, result.Rejected)
+	}
+	if result.Items[0].Reason != "queue full" || result.Items[1].Reason != "bad" {
+		t.Errorf("rejectAll reasons = %q, %q", result.Items[0].Reason, result.Items[1].Reason)
+	}
+}
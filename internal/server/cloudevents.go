@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cloudedugcp/responseEngine/internal/decoder"
+)
+
+// cloudEvent - CloudEvent 1.0 у structured-режимі (або зібраний із заголовків binary-режиму)
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            string          `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+	DataBase64      []byte          `json:"data_base64"`
+}
+
+// isCloudEvent - перевіряє, чи запит містить CloudEvent (binary або structured режим)
+func isCloudEvent(r *http.Request) bool {
+	return r.Header.Get("Ce-Specversion") != "" ||
+		strings.HasPrefix(r.Header.Get("Content-Type"), "application/cloudevents")
+}
+
+// parseCloudEvents - розбирає CloudEvents із запиту
+func parseCloudEvents(r *http.Request, body []byte) ([]cloudEvent, error) {
+	if v := r.Header.Get("Ce-Specversion"); v != "" {
+		return []cloudEvent{{
+			SpecVersion:     v,
+			ID:              r.Header.Get("Ce-Id"),
+			Source:          r.Header.Get("Ce-Source"),
+			Type:            r.Header.Get("Ce-Type"),
+			Subject:         r.Header.Get("Ce-Subject"),
+			Time:            r.Header.Get("Ce-Time"),
+			DataContentType: r.Header.Get("Content-Type"),
+			Data:            body,
+		}}, nil
+	}
+
+	var events []cloudEvent
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/cloudevents-batch+json") {
+		if err := json.Unmarshal(body, &events); err != nil {
+			return nil, fmt.Errorf("invalid CloudEvents batch: %v", err)
+		}
+	} else {
+		var ce cloudEvent
+		if err := json.Unmarshal(body, &ce); err != nil {
+			return nil, fmt.Errorf("invalid CloudEvent: %v", err)
+		}
+		events = []cloudEvent{ce}
+	}
+	for i := range events {
+		if events[i].SpecVersion == "" {
+			return nil, fmt.Errorf("CloudEvent #%d has no specversion", i)
+		}
+		if events[i].Data == nil && events[i].DataBase64 != nil {
+			events[i].Data = events[i].DataBase64
+			continue
+		}
+		// Не-JSON дані у structured-режимі передаються рядком JSON
+		if !strings.Contains(events[i].DataContentType, "json") && len(events[i].Data) > 0 && events[i].Data[0] == '"' {
+			var text string
+			if err := json.Unmarshal(events[i].Data, &text); err == nil {
+				events[i].Data = []byte(text)
+			}
+		}
+	}
+	return events, nil
+}
+
+// route - обирає джерело (і його декодер) за type/source CloudEvent
+func (s *Server) route(ce cloudEvent, source string) string {
+	for _, rt := range s.cfg.Server.CloudEvents.Routes {
+		if matchPrefix(rt.Type, ce.Type) && matchPrefix(rt.Source, ce.Source) {
+			return rt.To
+		}
+	}
+	return source
+}
+
+// matchPrefix - порожній шаблон збігається з усім, "abc*" - за префіксом, інакше точний збіг
+func matchPrefix(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(value, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == value
+}
+
+// cloudEventsHandler - обробляє CloudEvents; дані кожної події розбирає декодер обраного джерела
+func (s *Server) cloudEventsHandler(w http.ResponseWriter, r *http.Request, source string, dec decoder.Decoder, body []byte) {
+	events, err := parseCloudEvents(r, body)
+	if err != nil {
+		log.Printf("Invalid CloudEvent for %s: %v", source, err)
+		http.Error(w, "Invalid CloudEvent", http.StatusBadRequest)
+		return
+	}
+
+	// Подія, перенаправлена до іншого джерела, має пройти і його автентифікацію;
+	// кожне джерело перевіряється один раз, інакше HMAC-підпис запиту вважався б повтором
+	targets := make([]string, len(events))
+	authorized := map[string]bool{source: true}
+	for i, ce := range events {
+		targets[i] = source
+		if routed := s.route(ce, source); routed != source {
+			if _, ok := s.decoders[routed]; ok {
+				targets[i] = routed
+			} else {
+				log.Printf("CloudEvent route to %q has no decoder, using %s", routed, source)
+			}
+		}
+		if !authorized[targets[i]] {
+			if !s.authorize(w, r, targets[i], body) {
+				return
+			}
+			authorized[targets[i]] = true
+		}
+	}
+
+	var result batchResult
+	pb := s.newPendingBatch() // Усі CloudEvents запиту ставляться в чергу разом
+	for i, ce := range events {
+		target, d := targets[i], dec
+		if target != source {
+			d = s.decoders[target]
+		}
+		fields := map[string]string{
+			"ce.id":     ce.ID,
+			"ce.type":   ce.Type,
+			"ce.source": ce.Source,
+		}
+		if ce.Subject != "" {
+			fields["ce.subject"] = ce.Subject
+		}
+		result.merge(s.processItems(target, d, splitBatch(ce.Data), fields, pb))
+	}
+	s.enqueue(&result, pb)
+
+	status := http.StatusOK
+	if result.Accepted == 0 {
+		status = http.StatusBadRequest
+	}
+	s.respond(w, status, result)
+}
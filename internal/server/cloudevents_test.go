@@ -0,0 +1,204 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudedugcp/responseEngine/internal/config"
+	"github.com/cloudedugcp/responseEngine/internal/db"
+	"github.com/cloudedugcp/responseEngine/internal/decoder"
+)
+
+func TestParseCloudEvents(t *testing.T) {
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		body        string
+		wantTypes   []string
+		wantData    []string
+		wantErr     bool
+		wantSubject string
+	}{
+		{
+			name: "binary mode",
+			headers: map[string]string{
+				"Ce-Specversion": "1.0", "Ce-Id": "1", "Ce-Source": "/falco", "Ce-Type": "falco.alert", "Ce-Subject": "node-1",
+				"Content-Type": "application/json",
+			},
+			body:        `{"rule":"r"}`,
+			wantTypes:   []string{"falco.alert"},
+			wantData:    []string{`{"rule":"r"}`},
+			wantSubject: "node-1",
+		},
+		{
+			name:      "structured mode",
+			headers:   map[string]string{"Content-Type": "application/cloudevents+json; charset=utf-8"},
+			body:      `{"specversion":"1.0","id":"1","source":"/hubble","type":"io.cilium.hubble.flow","datacontenttype":"application/json","data":{"verdict":"DROPPED"}}`,
+			wantTypes: []string{"io.cilium.hubble.flow"},
+			wantData:  []string{`{"verdict":"DROPPED"}`},
+		},
+		{
+			name:      "structured mode with base64 data",
+			headers:   map[string]string{"Content-Type": "application/cloudevents+json"},
+			body:      `{"specversion":"1.0","id":"1","source":"/s","type":"t","data_base64":"eyJydWxlIjoiciJ9"}`,
+			wantTypes: []string{"t"},
+			wantData:  []string{`{"rule":"r"}`},
+		},
+		{
+			// Не-JSON дані передаються рядком і розгортаються
+			name:      "structured mode with text data",
+			headers:   map[string]string{"Content-Type": "application/cloudevents+json"},
+			body:      `{"specversion":"1.0","id":"1","source":"/syslog","type":"syslog","datacontenttype":"text/plain","data":"<13>1 - host app - - - text"}`,
+			wantTypes: []string{"syslog"},
+			wantData:  []string{`<13>1 - host app - - - text`},
+		},
+		{
+			name:    "batch mode",
+			headers: map[string]string{"Content-Type": "application/cloudevents-batch+json"},
+			body: `[{"specversion":"1.0","id":"1","source":"/a","type":"falco.alert","data":{"rule":"a"}},` +
+				`{"specversion":"1.0","id":"2","source":"/b","type":"io.cilium.hubble.flow","data":{"verdict":"DROPPED"}}]`,
+			wantTypes: []string{"falco.alert", "io.cilium.hubble.flow"},
+			wantData:  []string{`{"rule":"a"}`, `{"verdict":"DROPPED"}`},
+		},
+		{
+			name:    "missing specversion",
+			headers: map[string]string{"Content-Type": "application/cloudevents+json"},
+			body:    `{"id":"1","source":"/s","type":"t","data":{}}`,
+			wantErr: true,
+		},
+		{
+			name:    "batch item without specversion",
+			headers: map[string]string{"Content-Type": "application/cloudevents-batch+json"},
+			body:    `[{"specversion":"1.0","id":"1","type":"t"},{"id":"2","type":"t"}]`,
+			wantErr: true,
+		},
+		{name: "malformed structured", headers: map[string]string{"Content-Type": "application/cloudevents+json"}, body: `{"specversion":`, wantErr: true},
+		{name: "batch not a list", headers: map[string]string{"Content-Type": "application/cloudevents-batch+json"}, body: `{"specversion":"1.0"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(tt.body))
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if !isCloudEvent(r) {
+				t.Fatalf("request not recognised as a CloudEvent")
+			}
+			events, err := parseCloudEvents(r, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCloudEvents error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(events) != len(tt.wantTypes) {
+				t.Fatalf("got %d events, want %d", len(events), len(tt.wantTypes))
+			}
+			for i, ce := range events {
+				if ce.Type != tt.wantTypes[i] || string(ce.Data) != tt.wantData[i] {
+					t.Errorf("event %d: type, data = %q, %s; want %q, %s", i, ce.Type, ce.Data, tt.wantTypes[i], tt.wantData[i])
+				}
+			}
+			if tt.wantSubject != "" && events[0].Subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", events[0].Subject, tt.wantSubject)
+			}
+		})
+	}
+
+	plain := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{}`))
+	plain.Header.Set("Content-Type", "application/json")
+	if isCloudEvent(plain) {
+		t.Errorf("plain JSON request recognised as a CloudEvent")
+	}
+}
+
+func TestCloudEventsRoute(t *testing.T) {
+	s := &Server{cfg: &config.Config{Server: config.ServerConfig{CloudEvents: config.CloudEventsConfig{Routes: []config.CloudEventsRoute{
+		{Type: "falco.*", To: "falco_events"},
+		{Type: "io.cilium.hubble.flow", Source: "/cluster-a*", To: "hubble_a"},
+		{Source: "/suricata", To: "eve_events"},
+	}}}}}
+	tests := []struct {
+		ceType, ceSource string
+		want             string
+	}{
+		{"falco.alert", "/anything", "falco_events"},
+		{"falco", "/anything", "alias"}, // Префікс "falco." не збігся
+		{"io.cilium.hubble.flow", "/cluster-a/node-1", "hubble_a"},
+		{"io.cilium.hubble.flow", "/cluster-b", "alias"},
+		{"io.cilium.hubble.flow.v2", "/cluster-a", "alias"}, // Тип без * - точний збіг
+		{"any.type", "/suricata", "eve_events"},
+		{"unknown.type", "/unknown", "alias"},
+	}
+	for _, tt := range tests {
+		if got := s.route(cloudEvent{Type: tt.ceType, Source: tt.ceSource}, "alias"); got != tt.want {
+			t.Errorf("route(%s, %s) = %q, want %q", tt.ceType, tt.ceSource, got, tt.want)
+		}
+	}
+}
+
+func TestCloudEventsHandlerRouting(t *testing.T) {
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer database.Close()
+
+	cfg := &config.Config{Server: config.ServerConfig{
+		Aliases: map[string]string{"/events": "eve_events"},
+		CloudEvents: config.CloudEventsConfig{Routes: []config.CloudEventsRoute{
+			{Type: "falco.*", To: "falco_events"},
+			{Type: "missing.*", To: "no_decoder"},
+		}},
+	}}
+	eve, err := decoder.NewEveDecoder(nil)
+	if err != nil {
+		t.Fatalf("NewEveDecoder: %v", err)
+	}
+	decoders := map[string]decoder.Decoder{"eve_events": eve, "falco_events": decoder.NewFalcoDecoder(nil)}
+	s := NewServer(cfg, database, nil, decoders)
+
+	post := func(ceType, data string) (int, batchResult) {
+		r := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(data))
+		r.Header.Set("Ce-Specversion", "1.0")
+		r.Header.Set("Ce-Id", "1")
+		r.Header.Set("Ce-Source", "/test")
+		r.Header.Set("Ce-Type", ceType)
+		r.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		s.eventHandler(rec, r)
+		var result batchResult
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		return rec.Code, result
+	}
+
+	tests := []struct {
+		name         string
+		ceType, data string
+		wantStatus   int
+		wantAccepted int
+	}{
+		{name: "routed to falco decoder", ceType: "falco.alert", data: `{"rule":"r","output_fields":{"fd.rip":"203.0.113.7"}}`, wantStatus: http.StatusOK, wantAccepted: 1},
+		{name: "unknown type uses alias decoder", ceType: "org.example.ids", data: `{"event_type":"alert","src_ip":"203.0.113.8","alert":{"signature":"s"}}`, wantStatus: http.StatusOK, wantAccepted: 1},
+		{name: "route without decoder uses alias decoder", ceType: "missing.x", data: `{"event_type":"dns","src_ip":"203.0.113.9"}`, wantStatus: http.StatusOK, wantAccepted: 1},
+		// Тип без маршруту розбирає декодер аліасу; дані іншого формату відхиляються
+		{name: "unknown type with foreign data rejected", ceType: "org.example.other", data: `not json`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, result := post(tt.ceType, tt.data)
+			if code != tt.wantStatus || result.Accepted != tt.wantAccepted {
+				t.Errorf("status %d, accepted %d; want %d, %d", code, result.Accepted, tt.wantStatus, tt.wantAccepted)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{"id":"1"}`))
+	r.Header.Set("Content-Type", "application/cloudevents+json")
+	s.eventHandler(rec, r)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("CloudEvent without specversion: status %d, want 400", rec.Code)
+	}
+}
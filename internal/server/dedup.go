@@ -0,0 +1,91 @@
+package server
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+	"github.com/cloudedugcp/responseEngine/internal/config"
+)
+
+// defaultDedupFields - поля ключа дедуплікації джерела за замовчуванням
+var defaultDedupFields = []string{"ip", "rule"}
+
+// defaultScenarioDedupFields - поля ключа дедуплікації сценарію за замовчуванням; значення
+// ключа кореляції сценарію входить у ключ завжди
+var defaultScenarioDedupFields = []string{"rule"}
+
+// deduper - пам'ятає нещодавні події і визначає повтори
+type deduper struct {
+	window time.Duration
+	fields []string
+
+	mu        sync.Mutex
+	seen      map[dedupKey]time.Time // Ключ -> час першої події у вікні
+	lastPrune time.Time
+}
+
+// newDeduper - створює дедуплікатор; nil, якщо вікно не задано. defaults - поля ключа,
+// якщо fields не задано
+func newDeduper(cfg config.DedupConfig, defaults []string) *deduper {
+	if cfg.Window <= 0 {
+		return nil
+	}
+	fields := cfg.Fields
+	if len(fields) == 0 {
+		fields = defaults
+	}
+	return &deduper{
+		window: cfg.Window,
+		fields: fields,
+		seen:   make(map[dedupKey]time.Time),
+	}
+}
+
+// dedupKey - хеш полів події, за якими визначаються повтори
+type dedupKey [sha256.Size]byte
+
+// key - обчислює ключ дедуплікації події; prefix - додаткові значення ключа (наприклад, значення
+// ключа кореляції сценарію)
+func (d *deduper) key(event actioner.Event, prefix ...string) dedupKey {
+	h := sha256.New()
+	for _, v := range prefix {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	for _, f := range d.fields {
+		h.Write([]byte(event.Value(f)))
+		h.Write([]byte{0})
+	}
+	var key dedupKey
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// duplicate - true, якщо подію з таким ключем уже оброблено у поточному вікні
+func (d *deduper) duplicate(key dedupKey, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now.Sub(d.lastPrune) > d.window {
+		for k, first := range d.seen {
+			if now.Sub(first) >= d.window {
+				delete(d.seen, k)
+			}
+		}
+		d.lastPrune = now
+	}
+	first, ok := d.seen[key]
+	return ok && now.Sub(first) < d.window
+}
+
+// record - запам'ятовує успішно прийняту подію. Вікно відлічується від першої події,
+// тож безперервний потік повторів пропускає одну подію на вікно, а не придушується назавжди
+func (d *deduper) record(key dedupKey, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if first, ok := d.seen[key]; ok && now.Sub(first) < d.window {
+		return
+	}
+	d.seen[key] = now
+}
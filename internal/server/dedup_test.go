@@ -0,0 +1,199 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+	"github.com/cloudedugcp/responseEngine/internal/config"
+	"github.com/cloudedugcp/responseEngine/internal/db"
+	"github.com/cloudedugcp/responseEngine/internal/decoder"
+)
+
+func TestDeduperWindow(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		records []time.Duration // Моменти record від base
+		check   time.Duration
+		want    bool
+	}{
+		{name: "never recorded", check: time.Second, want: false},
+		{name: "within window", records: []time.Duration{0}, check: 59 * time.Second, want: true},
+		{name: "window boundary", records: []time.Duration{0}, check: time.Minute, want: false},
+		{name: "after window", records: []time.Duration{0}, check: 2 * time.Minute, want: false},
+		{
+			// Повтор у вікні не продовжує його - потік повторів пропускає одну подію на вікно
+			name:    "repeat does not extend window",
+			records: []time.Duration{0, 30 * time.Second},
+			check:   61 * time.Second,
+			want:    false,
+		},
+		{
+			name:    "new window after expiry",
+			records: []time.Duration{0, 70 * time.Second},
+			check:   100 * time.Second,
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDeduper(config.DedupConfig{Window: time.Minute}, defaultDedupFields)
+			key := d.key(actioner.Event{IP: "203.0.113.7", RuleName: "r"})
+			for _, at := range tt.records {
+				d.record(key, base.Add(at))
+			}
+			if got := d.duplicate(key, base.Add(tt.check)); got != tt.want {
+				t.Errorf("duplicate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeduperPrunesExpiredKeys(t *testing.T) {
+	d := newDeduper(config.DedupConfig{Window: time.Minute}, defaultDedupFields)
+	base := time.Now()
+	d.record(d.key(actioner.Event{IP: "203.0.113.7"}), base)
+	d.duplicate(d.key(actioner.Event{IP: "203.0.113.8"}), base.Add(2*time.Minute))
+	if len(d.seen) != 0 {
+		t.Errorf("%d expired keys kept", len(d.seen))
+	}
+}
+
+func TestNewDeduper(t *testing.T) {
+	if d := newDeduper(config.DedupConfig{Fields: []string{"ip"}}, defaultDedupFields); d != nil {
+		t.Errorf("deduper without window = %+v, want nil", d)
+	}
+	d := newDeduper(config.DedupConfig{Window: time.Minute}, defaultScenarioDedupFields)
+	if len(d.fields) != 1 || d.fields[0] != "rule" {
+		t.Errorf("default fields = %v, want [rule]", d.fields)
+	}
+}
+
+func TestDeduperKey(t *testing.T) {
+	event := actioner.Event{IP: "203.0.113.7", RuleName: "r", Priority: "Warning", Fields: map[string]string{"user": "root"}}
+	tests := []struct {
+		name   string
+		fields []string
+		a, b   actioner.Event
+		prefix [2][]string
+		same   bool
+	}{
+		{name: "same event", fields: []string{"ip", "rule"}, a: event, b: event, same: true},
+		{
+			name:   "field outside key ignored",
+			fields: []string{"ip", "rule"},
+			a:      event,
+			b:      actioner.Event{IP: event.IP, RuleName: event.RuleName, Priority: "Critical"},
+			same:   true,
+		},
+		{
+			name:   "field in key differs",
+			fields: []string{"ip", "rule"},
+			a:      event,
+			b:      actioner.Event{IP: "203.0.113.8", RuleName: event.RuleName},
+			same:   false,
+		},
+		{
+			name:   "output field in key",
+			fields: []string{"user"},
+			a:      event,
+			b:      actioner.Event{Fields: map[string]string{"user": "admin"}},
+			same:   false,
+		},
+		{
+			// Межі значень входять у хеш - зсув символу між полями дає інший ключ
+			name:   "values are separated",
+			fields: []string{"ip", "rule"},
+			a:      actioner.Event{IP: "ab", RuleName: "c"},
+			b:      actioner.Event{IP: "a", RuleName: "bc"},
+			same:   false,
+		},
+		{
+			name:   "prefix distinguishes scenarios",
+			fields: []string{"rule"},
+			a:      event,
+			b:      event,
+			prefix: [2][]string{{"ip", "203.0.113.7"}, {"ip", "203.0.113.8"}},
+			same:   false,
+		},
+		{
+			name:   "prefix separated from fields",
+			fields: []string{"rule"},
+			a:      actioner.Event{RuleName: "r"},
+			b:      actioner.Event{RuleName: ""},
+			prefix: [2][]string{{"x"}, {"x", "r"}},
+			same:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDeduper(config.DedupConfig{Window: time.Minute, Fields: tt.fields}, nil)
+			if same := d.key(tt.a, tt.prefix[0]...) == d.key(tt.b, tt.prefix[1]...); same != tt.same {
+				t.Errorf("keys equal = %v, want %v", same, tt.same)
+			}
+		})
+	}
+}
+
+func TestFailedEventNotDeduplicated(t *testing.T) {
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer database.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Aliases: map[string]string{"/falco": "falco_events"},
+			Sources: map[string]config.SourceConfig{
+				"falco_events": {Dedup: config.DedupConfig{Window: time.Minute}},
+			},
+		},
+		Scenarios: []config.Scenario{{
+			Name:      "block",
+			FalcoRule: "Suspicious",
+			Actioners: []config.ScenarioActioner{{Name: "store"}},
+			Dedup:     config.DedupConfig{Window: time.Minute},
+		}},
+	}
+	store := &stubActioner{name: "store", fail: true}
+	decoders := map[string]decoder.Decoder{"falco_events": decoder.NewFalcoDecoder(nil)}
+	s := NewServer(cfg, database, map[string]actioner.Actioner{"store": store}, decoders)
+
+	post := func() batchResult {
+		rec := httptest.NewRecorder()
+		body := `{"ip":"203.0.113.7","rule":"Suspicious"}`
+		s.eventHandler(rec, httptest.NewRequest(http.MethodPost, "/falco", strings.NewReader(body)))
+		var result batchResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("status %d, invalid result %q: %v", rec.Code, rec.Body, err)
+		}
+		return result
+	}
+
+	// Збій діяча: ні джерело, ні сценарій не запам'ятовують подію, тож повтор відправника виконується
+	if r := post(); r.Failed != 1 {
+		t.Fatalf("failing attempt: failed = %d, want 1", r.Failed)
+	}
+	store.fail = false
+	if r := post(); r.Failed != 0 || r.Suppressed != 0 {
+		t.Fatalf("retry: failed = %d, suppressed = %d; want neither", r.Failed, r.Suppressed)
+	}
+	if store.calls != 2 {
+		t.Errorf("store ran %d times, want 2 (failed attempt and retry)", store.calls)
+	}
+
+	// Після успіху повтор придушується
+	if r := post(); r.Suppressed != 1 {
+		t.Errorf("repeat after success: suppressed = %d, want 1", r.Suppressed)
+	}
+	if store.calls != 2 {
+		t.Errorf("store ran %d times after a successful attempt, want 2", store.calls)
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/config"
+	"github.com/cloudedugcp/responseEngine/internal/decoder"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	falcoSubMethod       = "/falco.outputs.service/sub"
+	falcoMinBackoff      = time.Second
+	falcoMaxBackoff      = time.Minute
+	falcoDefaultPoll     = time.Second
+	falcoDefaultGRPCName = "falco_grpc"
+)
+
+// falcoSubStream - опис двонаправленого потоку falco.outputs.service/sub
+var falcoSubStream = &grpc.StreamDesc{StreamName: "sub", ServerStreams: true, ClientStreams: true}
+
+// rawCodec - кодек gRPC, що передає повідомлення як є; розбір - у decoder.ParseFalcoOutput
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("raw codec: unexpected message type %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("raw codec: unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
+
+// startFalcoGRPC - запускає клієнтів gRPC outputs service Falco з конфігурації
+func (s *Server) startFalcoGRPC() error {
+	for _, fc := range s.cfg.Server.FalcoGRPC {
+		if fc.Source == "" {
+			fc.Source = falcoDefaultGRPCName
+		}
+		if fc.PollInterval <= 0 {
+			fc.PollInterval = falcoDefaultPoll
+		}
+		dec, ok := s.decoders[fc.Source]
+		if !ok {
+			if _, configured := s.cfg.Server.Sources[fc.Source]; configured {
+				return fmt.Errorf("falco_grpc %s: decoder for source %q is not available", fc.Address, fc.Source)
+			}
+			dec = decoder.NewFalcoDecoder(nil) // Джерело без налаштувань - декодер falco за замовчуванням
+		}
+		fd, ok := dec.(*decoder.FalcoDecoder)
+		if !ok {
+			return fmt.Errorf("falco_grpc %s: source %q uses %s decoder, falco_grpc needs falco", fc.Address, fc.Source, dec.Name())
+		}
+
+		creds, err := falcoCredentials(fc)
+		if err != nil {
+			return err
+		}
+		conn, err := grpc.NewClient(fc.Address, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return fmt.Errorf("failed to create Falco gRPC client for %s: %v", fc.Address, err)
+		}
+		go s.runFalcoGRPC(conn, fd, fc)
+		log.Printf("Subscribing to Falco gRPC outputs at %s, source %s", fc.Address, fc.Source)
+	}
+	return nil
+}
+
+// falcoCredentials - unix-сокет без шифрування або mTLS для TCP; відкритий TCP - лише з insecure: true
+func falcoCredentials(fc config.FalcoGRPCConfig) (credentials.TransportCredentials, error) {
+	if strings.HasPrefix(fc.Address, "unix:") {
+		return insecure.NewCredentials(), nil
+	}
+	if fc.CertFile == "" {
+		if !fc.Insecure {
+			return nil, fmt.Errorf("falco_grpc %s: TCP connection requires cert_file and key_file for mTLS (or insecure: true)", fc.Address)
+		}
+		log.Printf("Warning: falco_grpc %s connects over plaintext TCP (insecure: true)", fc.Address)
+		return insecure.NewCredentials(), nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(fc.CertFile, fc.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Falco gRPC client certificate: %v", err)
+	}
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ServerName:   fc.ServerName,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if fc.CAFile != "" {
+		ca, err := os.ReadFile(fc.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Falco gRPC CA file: %v", err)
+		}
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in Falco gRPC CA file %s", fc.CAFile)
+		}
+	}
+	return credentials.NewTLS(tlsCfg), nil
+}
+
+// runFalcoGRPC - підтримує підписку на виходи Falco з перепідключенням і експоненційною затримкою
+func (s *Server) runFalcoGRPC(conn *grpc.ClientConn, fd *decoder.FalcoDecoder, fc config.FalcoGRPCConfig) {
+	backoff := falcoMinBackoff
+	for {
+		received, err := s.subscribeFalco(conn, fd, fc)
+		if received > 0 {
+			backoff = falcoMinBackoff
+		}
+		log.Printf("Falco gRPC stream from %s ended after %d alerts: %v; reconnecting in %s", fc.Address, received, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > falcoMaxBackoff {
+			backoff = falcoMaxBackoff
+		}
+	}
+}
+
+// subscribeFalco - відкриває потік sub і обробляє сповіщення до його завершення
+func (s *Server) subscribeFalco(conn *grpc.ClientConn, fd *decoder.FalcoDecoder, fc config.FalcoGRPCConfig) (int, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, falcoSubStream, falcoSubMethod, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return 0, err
+	}
+
+	// Falco надсилає нові виходи у відповідь на запити, тому періодично надсилаємо порожній request
+	go func() {
+		ticker := time.NewTicker(fc.PollInterval)
+		defer ticker.Stop()
+		for {
+			req := []byte{}
+			if err := stream.SendMsg(&req); err != nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	received := 0
+	for {
+		var msg []byte
+		if err := stream.RecvMsg(&msg); err != nil {
+			return received, err
+		}
+		received++
+		alert, err := decoder.ParseFalcoOutput(msg)
+		if err != nil {
+			log.Printf("Failed to decode Falco gRPC output: %v", err)
+			continue
+		}
+		event := fd.Event(alert)
+		event.Source = fc.Source
+		if event.Time.IsZero() {
+			event.Time = time.Now()
+		}
+		status, adm := s.admit(fc.Source, event)
+		if adm != nil {
+			adm.commit() // dispatch чекає на місце в черзі, тож подію не буде відхилено
+		}
+		if status == admitOK {
+			s.dispatch(s.persistOne(event))
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/cloudedugcp/responseEngine/internal/decoder"
+)
+
+// pushEnvelope - конверт push-підписки Google Cloud Pub/Sub
+type pushEnvelope struct {
+	Message struct {
+		Data        []byte            `json:"data"` // base64 розкодовується автоматично
+		Attributes  map[string]string `json:"attributes"`
+		MessageID   string            `json:"messageId"`
+		PublishTime string            `json:"publishTime"`
+	} `json:"message"`
+	Subscription string `json:"subscription"`
+}
+
+// pubsubHandler - обробляє push-повідомлення Pub/Sub.
+// Pub/Sub вважає повідомлення підтвердженим лише при 2xx, тому на збій діячів
+// повертається 500 (повідомлення буде доставлено повторно), а на непридатне
+// до розбору повідомлення - 400 (варто налаштувати dead-letter topic).
+// Повідомлення обробляються синхронно навіть за наявності черги: 202 підтвердив би
+// повідомлення ще до виконання діячів, і про їхній збій Pub/Sub вже не дізнався б.
+// Паралельністю та повторами тут керує сама підписка
+func (s *Server) pubsubHandler(w http.ResponseWriter, r *http.Request, source string, dec decoder.Decoder, body []byte) {
+	var env pushEnvelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		log.Printf("Invalid Pub/Sub push envelope for %s: %v", source, err)
+		http.Error(w, "Invalid Pub/Sub push envelope", http.StatusBadRequest)
+		return
+	}
+	if env.Message.MessageID == "" && len(env.Message.Data) == 0 {
+		log.Printf("Pub/Sub push for %s has no message", source)
+		http.Error(w, "Missing Pub/Sub message", http.StatusBadRequest)
+		return
+	}
+
+	// Атрибут повідомлення може вказувати джерело, чий декодер слід використати;
+	// тоді подія належить цьому джерелу (сценарії з source, dedup і ліміти швидкості),
+	// тож запит має пройти і його автентифікацію
+	routed := source
+	if attr := s.cfg.Server.Sources[source].PubSub.DecoderAttribute; attr != "" {
+		if name := env.Message.Attributes[attr]; name != "" {
+			if d, ok := s.decoders[name]; ok {
+				dec, routed = d, name
+			} else {
+				log.Printf("Pub/Sub message %s requests unknown decoder source %q, using %s", env.Message.MessageID, name, dec.Name())
+			}
+		}
+	}
+	if routed != source && !s.authorize(w, r, routed, body) {
+		return
+	}
+
+	fields := map[string]string{
+		"pubsub.message_id":   env.Message.MessageID,
+		"pubsub.subscription": env.Subscription,
+	}
+	for k, v := range env.Message.Attributes {
+		fields["pubsub.attributes."+k] = v
+	}
+
+	items := splitBatch(env.Message.Data)
+	if len(items) == 0 {
+		log.Printf("Pub/Sub message %s for %s has empty data", env.Message.MessageID, source)
+		http.Error(w, "Empty Pub/Sub message data", http.StatusBadRequest)
+		return
+	}
+
+	result := s.processItems(routed, dec, items, fields, nil)
+	status := http.StatusOK
+	switch {
+	case result.Accepted == 0:
+		status = http.StatusBadRequest
+	case result.Failed > 0:
+		status = http.StatusInternalServerError
+		log.Printf("Pub/Sub message %s for %s failed, requesting redelivery", env.Message.MessageID, source)
+	}
+	s.respond(w, status, result)
+}
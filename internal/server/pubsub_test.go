@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+	"github.com/cloudedugcp/responseEngine/internal/config"
+	"github.com/cloudedugcp/responseEngine/internal/db"
+	"github.com/cloudedugcp/responseEngine/internal/decoder"
+)
+
+func TestPubSubProcessedSynchronously(t *testing.T) {
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer database.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Aliases: map[string]string{"/pubsub": "pubsub_events"},
+			Sources: map[string]config.SourceConfig{
+				"pubsub_events": {PubSub: config.PubSubConfig{Enabled: true}},
+			},
+			Queue: config.QueueConfig{Workers: 1},
+		},
+		Scenarios: []config.Scenario{{
+			Name:      "block",
+			FalcoRule: "Suspicious",
+			Actioners: []config.ScenarioActioner{{Name: "store"}},
+		}},
+	}
+	store := &stubActioner{name: "store"}
+	decoders := map[string]decoder.Decoder{"pubsub_events": decoder.NewFalcoDecoder(nil)}
+	s := NewServer(cfg, database, map[string]actioner.Actioner{"store": store}, decoders)
+
+	data := base64.StdEncoding.EncodeToString([]byte(`{"ip":"203.0.113.7","rule":"Suspicious"}`))
+	body := `{"message":{"data":"` + data + `","messageId":"1"},"subscription":"sub"}`
+	tests := []struct {
+		name string
+		fail bool
+		want int
+	}{
+		{name: "actioner succeeds", want: http.StatusOK},
+		{name: "actioner fails", fail: true, want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.fail, store.calls = tt.fail, 0
+			rec := httptest.NewRecorder()
+			s.eventHandler(rec, httptest.NewRequest(http.MethodPost, "/pubsub", strings.NewReader(body)))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if store.calls != 1 {
+				t.Errorf("actioner ran %d times before the response, want 1", store.calls)
+			}
+			if s.queue.depth() != 0 {
+				t.Errorf("Pub/Sub event left %d items in the queue", s.queue.depth())
+			}
+		})
+	}
+}
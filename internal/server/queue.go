@@ -0,0 +1,279 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+	"github.com/cloudedugcp/responseEngine/internal/config"
+	"github.com/cloudedugcp/responseEngine/internal/db"
+	"github.com/cloudedugcp/responseEngine/internal/metrics"
+)
+
+const (
+	defaultQueueSize       = 1000
+	defaultQueueRetryAfter = 5 * time.Second
+	defaultMaxAttempts     = 3
+	retryBaseDelay         = 5 * time.Second // Затримка першого повтору; далі подвоюється
+)
+
+// queuedEvent - подія разом з ідентифікатором її запису в durable-черзі (0 - не збережена)
+type queuedEvent struct {
+	id        int64
+	event     actioner.Event
+	attempts  int             // Завершені невдалі спроби
+	evaluated bool            // Сценарії вже оцінено - лишилося виконати actions
+	actions   []pendingAction // Діячі, що ще не виконалися успішно
+	sync      bool            // Оброблюється в запиті: про збій дізнається відправник, у черзі не повторюється
+}
+
+// eventQueue - обмежена черга подій для асинхронної обробки
+type eventQueue struct {
+	mu         sync.Mutex
+	notEmpty   *sync.Cond
+	notFull    *sync.Cond
+	items      []queuedEvent
+	size       int
+	workers    int
+	retryAfter time.Duration
+	fullStatus int
+}
+
+// newEventQueue - створює чергу за налаштуваннями; nil, якщо обробка синхронна
+func newEventQueue(cfg config.QueueConfig) *eventQueue {
+	if cfg.Workers <= 0 {
+		return nil
+	}
+	q := &eventQueue{
+		size:       cfg.Size,
+		workers:    cfg.Workers,
+		retryAfter: cfg.RetryAfter,
+		fullStatus: cfg.FullStatus,
+	}
+	if q.size <= 0 {
+		q.size = defaultQueueSize
+	}
+	if q.retryAfter <= 0 {
+		q.retryAfter = defaultQueueRetryAfter
+	}
+	if q.fullStatus == 0 {
+		q.fullStatus = 429
+	}
+	q.notEmpty = sync.NewCond(&q.mu)
+	q.notFull = sync.NewCond(&q.mu)
+	return q
+}
+
+// tryPush - додає всі події або жодної, якщо для них немає місця
+func (q *eventQueue) tryPush(events []queuedEvent) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items)+len(events) > q.size {
+		return false
+	}
+	q.items = append(q.items, events...)
+	q.notEmpty.Broadcast()
+	return true
+}
+
+// push - додає подію, чекаючи на вільне місце (для джерел, що вміють чекати, як-от gRPC-потік)
+func (q *eventQueue) push(event queuedEvent) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) >= q.size {
+		q.notFull.Wait()
+	}
+	q.items = append(q.items, event)
+	q.notEmpty.Signal()
+}
+
+// pop - забирає наступну подію, чекаючи, доки вона з'явиться
+func (q *eventQueue) pop() queuedEvent {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 {
+		q.notEmpty.Wait()
+	}
+	event := q.items[0]
+	q.items[0] = queuedEvent{}
+	q.items = q.items[1:]
+	q.notFull.Signal()
+	return event
+}
+
+// depth - кількість подій, що очікують обробки
+func (q *eventQueue) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// startWorkers - запускає пул обробників черги
+func (s *Server) startWorkers() {
+	q := s.queue
+	metrics.Gauge("responseengine_queue_depth", func() int64 { return int64(q.depth()) })
+	metrics.Gauge("responseengine_queue_capacity", func() int64 { return int64(q.size) })
+	for i := 0; i < q.workers; i++ {
+		go func() {
+			for {
+				qe := q.pop()
+				if _, err := s.runEvent(qe); err != nil {
+					log.Printf("Failed to process queued %s event (Rule=%s, IP=%s): %v", qe.event.Source, qe.event.RuleName, qe.event.IP, err)
+				}
+			}
+		}()
+	}
+	log.Printf("Started %d event workers (queue size %d)", q.workers, q.size)
+}
+
+// persist - зберігає події в durable-черзі; при помилці БД події обробляються без збереження
+func (s *Server) persist(events ...actioner.Event) []queuedEvent {
+	queued := make([]queuedEvent, len(events))
+	for i, event := range events {
+		queued[i].event = event
+	}
+	if !s.durable || len(events) == 0 {
+		return queued
+	}
+
+	payloads := make([][]byte, len(events))
+	for i, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("Failed to serialize event for durable queue: %v", err)
+			return queued
+		}
+		payloads[i] = payload
+	}
+	ids, err := s.db.EnqueueEvents(payloads, time.Now())
+	if err != nil {
+		log.Printf("Failed to persist %d events, processing without durability: %v", len(events), err)
+		return queued
+	}
+	for i, id := range ids {
+		queued[i].id = id
+	}
+	return queued
+}
+
+// persistOne - зберігає одну подію в durable-черзі (див. persist)
+func (s *Server) persistOne(event actioner.Event) queuedEvent {
+	return s.persist(event)[0]
+}
+
+// discard - прибирає з durable-черги події, які так і не потрапили в обробку
+func (s *Server) discard(queued []queuedEvent) {
+	for _, qe := range queued {
+		if qe.id != 0 {
+			s.db.CompleteEvents(qe.id)
+		}
+	}
+}
+
+// runEvent - обробляє подію і знімає її з durable-черги після успіху всіх діячів;
+// при збої діячів подія повторюється (retryEvent). Оцінена подія зберігає в черзі етап
+// і список діячів, тож після перезапуску сценарії не оцінюються і журнал не поповнюється вдруге
+func (s *Server) runEvent(qe queuedEvent) ([]string, error) {
+	if qe.id != 0 {
+		s.db.StartEvent(qe.id)
+	}
+	var triggered []string
+	if !qe.evaluated {
+		triggered, qe.actions = s.processEvent(qe)
+		qe.evaluated = true
+		if qe.id != 0 && len(qe.actions) > 0 {
+			s.saveActions(qe.id, qe.actions)
+		}
+	}
+	failed := s.runActions(qe.id, qe.actions)
+	if len(failed) == 0 {
+		if qe.id != 0 {
+			s.db.CompleteEvents(qe.id)
+		}
+		return triggered, nil
+	}
+	if qe.id != 0 {
+		s.saveActions(qe.id, failed)
+	}
+	err := actionsError(failed)
+	s.retryEvent(qe, failed, err)
+	return triggered, err
+}
+
+// saveActions - зберігає в durable-черзі діячів події, що ще не виконалися
+func (s *Server) saveActions(id int64, actions []pendingAction) {
+	payload, err := json.Marshal(actions)
+	if err != nil {
+		log.Printf("Failed to serialize actions of queued event %d: %v", id, err)
+		return
+	}
+	s.db.SaveEventActions(id, payload)
+}
+
+// retryEvent - повертає діячів, що збоїли, у чергу з наростаючою затримкою, доки не вичерпано
+// max_attempts. Без черги (або при синхронній обробці) про збій дізнається відправник (і може
+// повторити сам), тож подія одразу позначається як невдала
+func (s *Server) retryEvent(qe queuedEvent, failed []pendingAction, err error) {
+	qe.attempts++
+	queued := s.queue != nil && !qe.sync
+	if !queued || qe.attempts >= s.maxAttempts {
+		if queued {
+			log.Printf("Giving up on %s event (Rule=%s, IP=%s) after %d attempts: %v", qe.event.Source, qe.event.RuleName, qe.event.IP, qe.attempts, err)
+			metrics.Inc("responseengine_queue_failed_total", "source", qe.event.Source)
+		}
+		if qe.id != 0 {
+			s.db.FailEvent(qe.id, err.Error())
+		}
+		return
+	}
+	qe.actions = failed
+	delay := retryBaseDelay << (qe.attempts - 1)
+	log.Printf("Retrying %d failed actioners for %s event (Rule=%s, IP=%s) in %s (attempt %d of %d)", len(failed), qe.event.Source, qe.event.RuleName, qe.event.IP, delay, qe.attempts+1, s.maxAttempts)
+	metrics.Inc("responseengine_queue_retried_total", "source", qe.event.Source)
+	time.AfterFunc(delay, func() { s.queue.push(qe) })
+}
+
+// replayQueue - повторно обробляє події, не завершені до перезапуску
+func (s *Server) replayQueue() error {
+	pending, err := s.db.PendingEvents()
+	if err != nil {
+		return fmt.Errorf("failed to load queued events: %v", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	log.Printf("Replaying %d unfinished events from durable queue", len(pending))
+	for _, p := range pending {
+		if p.Attempts >= s.maxAttempts {
+			log.Printf("Giving up on queued event %d after %d attempts", p.ID, p.Attempts)
+			s.db.FailEvent(p.ID, fmt.Sprintf("gave up after %d attempts", p.Attempts))
+			continue
+		}
+		var event actioner.Event
+		if err := json.Unmarshal(p.Payload, &event); err != nil {
+			log.Printf("Failed to decode queued event %d: %v", p.ID, err)
+			s.db.FailEvent(p.ID, err.Error())
+			continue
+		}
+		qe := queuedEvent{id: p.ID, event: event, attempts: p.Attempts}
+		if p.Stage == db.StageActing {
+			if err := json.Unmarshal(p.Actions, &qe.actions); err != nil {
+				log.Printf("Failed to decode actions of queued event %d: %v", p.ID, err)
+				s.db.FailEvent(p.ID, err.Error())
+				continue
+			}
+			qe.evaluated = true
+		}
+		if s.queue != nil {
+			s.queue.push(qe)
+			continue
+		}
+		if _, err := s.runEvent(qe); err != nil {
+			log.Printf("Failed to process replayed event %d: %v", p.ID, err)
+		}
+	}
+	return nil
+}
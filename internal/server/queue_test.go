@@ -0,0 +1,244 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+	"github.com/cloudedugcp/responseEngine/internal/config"
+	"github.com/cloudedugcp/responseEngine/internal/db"
+	"github.com/cloudedugcp/responseEngine/internal/decoder"
+	"github.com/cloudedugcp/responseEngine/internal/scenario"
+)
+
+// stubActioner - діяч, що рахує виклики і завершується помилкою, доки fail = true
+type stubActioner struct {
+	name  string
+	fail  bool
+	calls int
+}
+
+func (a *stubActioner) Execute(actioner.Event, map[string]interface{}) error {
+	a.calls++
+	if a.fail {
+		return errors.New("stub failure")
+	}
+	return nil
+}
+
+func (a *stubActioner) Name() string { return a.name }
+
+func TestReplayRunsOnlyRemainingActioners(t *testing.T) {
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer database.Close()
+
+	cfg := &config.Config{
+		Scenarios: []config.Scenario{{
+			Name:      "block",
+			FalcoRule: "Suspicious",
+			Actioners: []config.ScenarioActioner{{Name: "store"}, {Name: "notify"}},
+		}},
+	}
+	store := &stubActioner{name: "store"}
+	notify := &stubActioner{name: "notify", fail: true}
+	actioners := map[string]actioner.Actioner{"store": store, "notify": notify}
+
+	// Перший запуск: черга без обробників, діяч notify збоїть
+	cfg.Server.Queue = config.QueueConfig{Workers: 1, Durable: true, MaxAttempts: 5}
+	s := NewServer(cfg, database, actioners, nil)
+	event := actioner.Event{IP: "203.0.113.7", RuleName: "Suspicious", Time: time.Now()}
+	if _, err := s.runEvent(s.persist(event)[0]); err == nil {
+		t.Fatalf("runEvent succeeded with a failing actioner")
+	}
+
+	// Перезапуск: синхронна обробка, notify вже працює
+	notify.fail = false
+	cfg.Server.Queue = config.QueueConfig{Durable: true, MaxAttempts: 5}
+	s = NewServer(cfg, database, actioners, nil)
+	if err := s.replayQueue(); err != nil {
+		t.Fatalf("replayQueue: %v", err)
+	}
+
+	if store.calls != 1 {
+		t.Errorf("store ran %d times, want 1", store.calls)
+	}
+	if notify.calls != 2 {
+		t.Errorf("notify ran %d times, want 2", notify.calls)
+	}
+	if n, _ := database.CountEventsByKey("ip", event.IP, "", time.Hour); n != 1 {
+		t.Errorf("history has %d events after replay, want 1", n)
+	}
+	if pending, _ := database.PendingEvents(); len(pending) != 0 {
+		t.Errorf("%d events left in queue after replay", len(pending))
+	}
+}
+
+func TestReplayAfterCrashDoesNotRecountHistory(t *testing.T) {
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer database.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Queue: config.QueueConfig{Durable: true}},
+		Scenarios: []config.Scenario{{
+			Name:      "block",
+			FalcoRule: "Suspicious",
+			Actioners: []config.ScenarioActioner{{Name: "store"}},
+		}},
+	}
+	store := &stubActioner{name: "store"}
+	s := NewServer(cfg, database, map[string]actioner.Actioner{"store": store}, nil)
+
+	// Збій до збереження етапу: подію вже записано в журнал, але не оцінено
+	event := actioner.Event{IP: "203.0.113.7", RuleName: "Suspicious", Time: time.Now()}
+	qe := s.persist(event)[0]
+	s.processEvent(qe)
+
+	if err := s.replayQueue(); err != nil {
+		t.Fatalf("replayQueue: %v", err)
+	}
+	if n, _ := database.CountEventsByKey("ip", event.IP, "", time.Hour); n != 1 {
+		t.Errorf("history has %d events after replay, want 1", n)
+	}
+	if store.calls != 1 {
+		t.Errorf("store ran %d times, want 1", store.calls)
+	}
+}
+
+func TestReplayAfterCrashDoesNotAdvanceSequence(t *testing.T) {
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer database.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Queue: config.QueueConfig{Durable: true}},
+		Scenarios: []config.Scenario{{
+			Name: "repeat",
+			Sequence: &scenario.SequenceConfig{
+				Steps:  []scenario.RuleMatch{{Rules: []string{"Suspicious"}}, {Rules: []string{"Suspicious"}}},
+				Within: time.Hour,
+			},
+			Actioners: []config.ScenarioActioner{{Name: "store"}},
+		}},
+	}
+	store := &stubActioner{name: "store"}
+	s := NewServer(cfg, database, map[string]actioner.Actioner{"store": store}, nil)
+
+	// Збій до збереження етапу: перший крок уже зараховано
+	event := actioner.Event{IP: "203.0.113.7", RuleName: "Suspicious", Time: time.Now()}
+	s.processEvent(s.persist(event)[0])
+
+	if err := s.replayQueue(); err != nil {
+		t.Fatalf("replayQueue: %v", err)
+	}
+	if store.calls != 0 {
+		t.Errorf("replayed event completed the sequence: store ran %d times", store.calls)
+	}
+
+	// Наступна подія - справжній другий крок
+	s.runEvent(s.persist(event)[0])
+	if store.calls != 1 {
+		t.Errorf("second event: store ran %d times, want 1", store.calls)
+	}
+}
+
+func TestBatchLargerThanQueue(t *testing.T) {
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer database.Close()
+
+	cfg := &config.Config{Server: config.ServerConfig{
+		Aliases: map[string]string{"/falco": "falco_events"},
+		Queue:   config.QueueConfig{Workers: 1, Size: 2},
+	}}
+	decoders := map[string]decoder.Decoder{"falco_events": decoder.NewFalcoDecoder(nil)}
+	s := NewServer(cfg, database, nil, decoders)
+
+	tests := []struct {
+		name   string
+		events int
+		want   int
+	}{
+		{name: "fits", events: 2, want: http.StatusAccepted},
+		{name: "larger than queue", events: 3, want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.queue.items = nil
+			body := strings.Repeat(`{"ip":"203.0.113.7","rule":"r"}`+"\n", tt.events)
+			rec := httptest.NewRecorder()
+			s.eventHandler(rec, httptest.NewRequest(http.MethodPost, "/falco", strings.NewReader(body)))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if rec.Code == http.StatusRequestEntityTooLarge && rec.Header().Get("Retry-After") != "" {
+				t.Errorf("oversized batch must not ask for a retry")
+			}
+		})
+	}
+}
+
+func TestCloudEventsBatchQueuedAsOneRequest(t *testing.T) {
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer database.Close()
+
+	cfg := &config.Config{Server: config.ServerConfig{
+		Aliases: map[string]string{"/falco": "falco_events"},
+		Queue:   config.QueueConfig{Workers: 1, Size: 3},
+	}}
+	decoders := map[string]decoder.Decoder{"falco_events": decoder.NewFalcoDecoder(nil)}
+	s := NewServer(cfg, database, nil, decoders)
+
+	ce := `{"specversion":"1.0","id":"%d","type":"falco.alert","source":"falco","data":{"ip":"203.0.113.%d","rule":"r"}}`
+	batch := func(n int) string {
+		var parts []string
+		for i := 0; i < n; i++ {
+			parts = append(parts, fmt.Sprintf(ce, i, i))
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	}
+	tests := []struct {
+		name      string
+		preloaded int // Подій у черзі до запиту
+		events    int
+		want      int
+		wantDepth int
+	}{
+		{name: "fits", events: 3, want: http.StatusAccepted, wantDepth: 3},
+		{name: "later event does not fit", preloaded: 1, events: 3, want: http.StatusTooManyRequests, wantDepth: 1},
+		{name: "request larger than queue", events: 4, want: http.StatusRequestEntityTooLarge, wantDepth: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.queue.items = make([]queuedEvent, tt.preloaded)
+			r := httptest.NewRequest(http.MethodPost, "/falco", strings.NewReader(batch(tt.events)))
+			r.Header.Set("Content-Type", "application/cloudevents-batch+json")
+			rec := httptest.NewRecorder()
+			s.eventHandler(rec, r)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if d := s.queue.depth(); d != tt.wantDepth {
+				t.Errorf("queue depth = %d, want %d", d, tt.wantDepth)
+			}
+		})
+	}
+}
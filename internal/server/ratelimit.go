@@ -0,0 +1,302 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+	"github.com/cloudedugcp/responseEngine/internal/config"
+	"github.com/cloudedugcp/responseEngine/internal/metrics"
+)
+
+const (
+	fieldRateLimitCount = "ratelimit.count" // Поле підсумкової події: скільки подій вона об'єднує
+
+	defaultAggregateWindow = time.Minute
+	defaultMaxDelay        = time.Minute
+	limiterIdleTTL         = 10 * time.Minute
+)
+
+// keyedLimiters - набір token bucket за ключем (адреса клієнта, IP події)
+type keyedLimiters struct {
+	limit rate.Limit
+	burst int
+
+	mu        sync.Mutex
+	limiters  map[string]*keyedLimiter
+	lastPrune time.Time
+}
+
+type keyedLimiter struct {
+	*rate.Limiter
+	lastUsed time.Time
+}
+
+// newKeyedLimiters - створює набір лімітів; nil, якщо ліміт не задано
+func newKeyedLimiters(cfg config.RateConfig) *keyedLimiters {
+	if cfg.Rate <= 0 {
+		return nil
+	}
+	return &keyedLimiters{
+		limit:    rate.Limit(cfg.Rate),
+		burst:    burstOf(cfg),
+		limiters: make(map[string]*keyedLimiter),
+	}
+}
+
+// get - повертає token bucket для ключа, прибираючи давно невикористані
+func (k *keyedLimiters) get(key string, now time.Time) *rate.Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if now.Sub(k.lastPrune) > limiterIdleTTL {
+		for key, l := range k.limiters {
+			if now.Sub(l.lastUsed) > limiterIdleTTL {
+				delete(k.limiters, key)
+			}
+		}
+		k.lastPrune = now
+	}
+	l, ok := k.limiters[key]
+	if !ok {
+		l = &keyedLimiter{Limiter: rate.NewLimiter(k.limit, k.burst)}
+		k.limiters[key] = l
+	}
+	l.lastUsed = now
+	return l.Limiter
+}
+
+// burstOf - розмір bucket; за замовчуванням - секундна норма, але не менше 1
+func burstOf(cfg config.RateConfig) int {
+	if cfg.Burst > 0 {
+		return cfg.Burst
+	}
+	if cfg.Rate < 1 {
+		return 1
+	}
+	return int(cfg.Rate)
+}
+
+// aggregate - події, що перевищили ліміт, накопичені для підсумкової події
+type aggregate struct {
+	event       actioner.Event
+	count       int
+	first, last time.Time
+}
+
+// sourceLimiter - ліміти подій одного джерела та поведінка при їх перевищенні
+type sourceLimiter struct {
+	source   string
+	alias    *rate.Limiter
+	clients  *keyedLimiters
+	ips      *keyedLimiters
+	onExcess string
+	window   time.Duration
+	maxDelay time.Duration
+	persist  func(actioner.Event) queuedEvent // Зберігає подію в durable-черзі до надсилання
+	dispatch func(queuedEvent)
+
+	mu         sync.Mutex
+	aggregates map[string]*aggregate
+}
+
+// newSourceLimiter - створює ліміти джерела; nil, якщо жоден ліміт не задано. Відкладені
+// та підсумкові події зберігаються через persist і передаються на обробку через dispatch
+func newSourceLimiter(source string, cfg config.RateLimitConfig, persist func(actioner.Event) queuedEvent, dispatch func(queuedEvent)) (*sourceLimiter, error) {
+	l := &sourceLimiter{
+		source:     source,
+		clients:    newKeyedLimiters(cfg.Client),
+		ips:        newKeyedLimiters(cfg.IP),
+		onExcess:   cfg.OnExcess,
+		window:     cfg.AggregateWindow,
+		maxDelay:   cfg.MaxDelay,
+		persist:    persist,
+		dispatch:   dispatch,
+		aggregates: make(map[string]*aggregate),
+	}
+	if cfg.Alias.Rate > 0 {
+		l.alias = rate.NewLimiter(rate.Limit(cfg.Alias.Rate), burstOf(cfg.Alias))
+	}
+	if l.alias == nil && l.clients == nil && l.ips == nil {
+		return nil, nil
+	}
+	switch l.onExcess {
+	case "":
+		l.onExcess = "drop"
+	case "drop", "queue", "aggregate":
+	default:
+		return nil, fmt.Errorf("source %s: unknown rate_limit on_excess %q (expected drop, queue or aggregate)", source, cfg.OnExcess)
+	}
+	if l.window <= 0 {
+		l.window = defaultAggregateWindow
+	}
+	if l.maxDelay <= 0 {
+		l.maxDelay = defaultMaxDelay
+	}
+	return l, nil
+}
+
+// allowClient - перевіряє ліміт запитів від клієнта; повертає час до наступної спроби
+func (l *sourceLimiter) allowClient(client string) (bool, time.Duration) {
+	if l.clients == nil {
+		return true, 0
+	}
+	r := l.clients.get(client, time.Now()).Reserve()
+	if d := r.Delay(); d > 0 {
+		r.Cancel()
+		metrics.Inc("responseengine_ratelimit_throttled_total", "source", l.source, "limit", "client", "action", "reject")
+		return false, d
+	}
+	return true, 0
+}
+
+// admission - рішення лімітів швидкості щодо події: зарезервовані токени і дія при перевищенні.
+// Рішення застосовується (commit) лише тоді, коли запит прийнято, інакше скасовується (cancel)
+type admission struct {
+	l            *sourceLimiter
+	event        actioner.Event
+	at           time.Time
+	reservations []*rate.Reservation
+	delay        time.Duration
+	limit        string
+}
+
+// allowed - true, якщо подію можна обробити зараз
+func (a *admission) allowed() bool {
+	return a.delay == 0
+}
+
+// check - визначає затримку події за всіма bucket (подію можна обробити, коли токени є
+// в усіх, тож затримка - найдовша з них) і резервує токени, якщо подію буде оброблено зараз
+// чи відкладено. Скасування резервації не повертає годинник bucket назад, тож подію, яку
+// буде відкинуто чи агреговано, не резервуємо зовсім
+func (l *sourceLimiter) check(event actioner.Event) *admission {
+	a := &admission{l: l, event: event, at: time.Now()}
+	var limiters []*rate.Limiter
+	var limits []string
+	if l.alias != nil {
+		limiters = append(limiters, l.alias)
+		limits = append(limits, "alias")
+	}
+	if l.ips != nil && event.IP != "" {
+		limiters = append(limiters, l.ips.get(event.IP, a.at))
+		limits = append(limits, "ip")
+	}
+	for i, lim := range limiters {
+		if tokens := lim.TokensAt(a.at); tokens < 1 {
+			d := time.Duration((1 - tokens) / float64(lim.Limit()) * float64(time.Second))
+			if d > a.delay {
+				a.delay, a.limit = d, limits[i]
+			}
+		}
+	}
+	if a.delay > 0 && !a.delayed() {
+		return a
+	}
+
+	a.delay = 0
+	for i, lim := range limiters {
+		r := lim.ReserveN(a.at, 1)
+		a.reservations = append(a.reservations, r)
+		if d := r.DelayFrom(a.at); d > a.delay {
+			a.delay, a.limit = d, limits[i]
+		}
+	}
+	return a
+}
+
+// delayed - true, якщо подію понад ліміт буде відкладено (on_excess: queue у межах max_delay)
+func (a *admission) delayed() bool {
+	return a.l.onExcess == "queue" && a.delay <= a.l.maxDelay
+}
+
+// cancel - повертає зарезервовані токени, якщо запит з подією не прийнято. Резервації
+// скасовуються у зворотному порядку, інакше пізніші не дали б повернути токени ранішим
+func (a *admission) cancel() {
+	for i := len(a.reservations) - 1; i >= 0; i-- {
+		a.reservations[i].CancelAt(a.at)
+	}
+	a.reservations = nil
+}
+
+// commit - застосовує рішення: подія понад ліміт відкидається, відкладається або додається
+// до підсумкової відповідно до on_excess. Відкладена подія одразу зберігається в durable-черзі
+// (якщо її ввімкнено), тож після перезапуску обробляється без очікування, а не губиться
+func (a *admission) commit() {
+	if a.allowed() {
+		return
+	}
+	l := a.l
+	metrics.Inc("responseengine_ratelimit_throttled_total", "source", l.source, "limit", a.limit, "action", l.onExcess)
+	switch {
+	case a.delayed():
+		qe := l.persist(a.event)
+		time.AfterFunc(a.delay, func() { l.dispatch(qe) })
+	case l.onExcess == "aggregate":
+		l.aggregate(a.event, a.at)
+	default:
+		log.Printf("Rate limit (%s) exceeded for %s, dropping event (Rule=%s, IP=%s)", a.limit, l.source, a.event.RuleName, a.event.IP)
+	}
+}
+
+// allow - перевіряє і одразу застосовує рішення лімітів; true, якщо подію можна обробити зараз
+func (l *sourceLimiter) allow(event actioner.Event) bool {
+	a := l.check(event)
+	a.commit()
+	return a.allowed()
+}
+
+// aggregate - накопичує подію; по закінченню вікна надсилається одна підсумкова подія
+func (l *sourceLimiter) aggregate(event actioner.Event, now time.Time) {
+	key := event.IP + "\x00" + event.RuleName
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if a, ok := l.aggregates[key]; ok {
+		a.count++
+		a.last = now
+		return
+	}
+	l.aggregates[key] = &aggregate{event: event, count: 1, first: now, last: now}
+	time.AfterFunc(l.window, func() { l.flush(key) })
+}
+
+// flush - надсилає підсумкову подію для накопичених подій
+func (l *sourceLimiter) flush(key string) {
+	l.mu.Lock()
+	a, ok := l.aggregates[key]
+	delete(l.aggregates, key)
+	l.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	summary := a.event
+	summary.Fields = make(map[string]string, len(a.event.Fields)+3)
+	for k, v := range a.event.Fields {
+		summary.Fields[k] = v
+	}
+	summary.Fields[fieldRateLimitCount] = strconv.Itoa(a.count)
+	summary.Fields["ratelimit.first"] = a.first.Format(time.RFC3339)
+	summary.Fields["ratelimit.last"] = a.last.Format(time.RFC3339)
+	summary.Log = fmt.Sprintf("%d events aggregated by rate limit between %s and %s: %s", a.count, a.first.Format(time.RFC3339), a.last.Format(time.RFC3339), a.event.Log)
+	summary.Time = a.last
+	log.Printf("Flushing %d rate-limited %s events (Rule=%s, IP=%s) as one summary event", a.count, l.source, summary.RuleName, summary.IP)
+	l.dispatch(l.persist(summary))
+}
+
+// aggregatedCount - скільки подій представляє подія в журналі: для підсумкової події джерела
+// з on_excess: aggregate - кількість агрегованих, для решти - одна
+func (l *sourceLimiter) aggregatedCount(event actioner.Event) int {
+	if l == nil || l.onExcess != "aggregate" {
+		return 1
+	}
+	n, err := strconv.Atoi(event.Fields[fieldRateLimitCount])
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
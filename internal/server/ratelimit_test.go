@@ -0,0 +1,282 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/actioner"
+	"github.com/cloudedugcp/responseEngine/internal/config"
+	"github.com/cloudedugcp/responseEngine/internal/db"
+	"github.com/cloudedugcp/responseEngine/internal/decoder"
+)
+
+// unpersisted - persist для лімітів без durable-черги
+func unpersisted(event actioner.Event) queuedEvent {
+	return queuedEvent{event: event}
+}
+
+func TestSourceLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          config.RateLimitConfig
+		events       []string // IP подій, по черзі
+		want         []bool
+		dispatched   int    // Скільки подій передано далі після очікування
+		summaryCount string // Для aggregate - ratelimit.count підсумкової події
+	}{
+		{
+			name:   "drop",
+			cfg:    config.RateLimitConfig{Alias: config.RateConfig{Rate: 0.001, Burst: 1}},
+			events: []string{"10.0.0.1", "10.0.0.1", "10.0.0.2"},
+			want:   []bool{true, false, false},
+		},
+		{
+			name:       "queue within max_delay",
+			cfg:        config.RateLimitConfig{Alias: config.RateConfig{Rate: 100, Burst: 1}, OnExcess: "queue"},
+			events:     []string{"10.0.0.1", "10.0.0.1"},
+			want:       []bool{true, false},
+			dispatched: 1,
+		},
+		{
+			name:   "queue beyond max_delay drops",
+			cfg:    config.RateLimitConfig{Alias: config.RateConfig{Rate: 0.001, Burst: 1}, OnExcess: "queue", MaxDelay: time.Second},
+			events: []string{"10.0.0.1", "10.0.0.1"},
+			want:   []bool{true, false},
+		},
+		{
+			name:         "aggregate",
+			cfg:          config.RateLimitConfig{Alias: config.RateConfig{Rate: 0.001, Burst: 1}, OnExcess: "aggregate", AggregateWindow: 50 * time.Millisecond},
+			events:       []string{"10.0.0.1", "10.0.0.1", "10.0.0.1", "10.0.0.1"},
+			want:         []bool{true, false, false, false},
+			dispatched:   1,
+			summaryCount: "3",
+		},
+		{
+			name:   "per ip",
+			cfg:    config.RateLimitConfig{IP: config.RateConfig{Rate: 0.001, Burst: 1}},
+			events: []string{"10.0.0.1", "10.0.0.2", "10.0.0.1", ""},
+			want:   []bool{true, true, false, true}, // Подія без IP не обмежується лімітом ip
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatched := make(chan actioner.Event, len(tt.events))
+			l, err := newSourceLimiter("test", tt.cfg, unpersisted, func(qe queuedEvent) { dispatched <- qe.event })
+			if err != nil || l == nil {
+				t.Fatalf("newSourceLimiter = %v, %v", l, err)
+			}
+			for i, ip := range tt.events {
+				if got := l.allow(actioner.Event{IP: ip, RuleName: "r"}); got != tt.want[i] {
+					t.Fatalf("event %d (%s): allow = %v, want %v", i, ip, got, tt.want[i])
+				}
+			}
+
+			var got []actioner.Event
+			timeout := time.After(time.Second)
+		wait:
+			for len(got) < tt.dispatched {
+				select {
+				case e := <-dispatched:
+					got = append(got, e)
+				case <-timeout:
+					break wait
+				}
+			}
+			select {
+			case e := <-dispatched:
+				got = append(got, e)
+			case <-time.After(100 * time.Millisecond):
+			}
+			if len(got) != tt.dispatched {
+				t.Fatalf("dispatched %d events, want %d", len(got), tt.dispatched)
+			}
+			if tt.summaryCount != "" && got[0].Field("ratelimit.count") != tt.summaryCount {
+				t.Errorf("summary ratelimit.count = %q, want %q", got[0].Field("ratelimit.count"), tt.summaryCount)
+			}
+		})
+	}
+}
+
+func TestSourceLimiterFlush(t *testing.T) {
+	var got []actioner.Event
+	l, err := newSourceLimiter("test", config.RateLimitConfig{
+		Alias:           config.RateConfig{Rate: 1},
+		OnExcess:        "aggregate",
+		AggregateWindow: time.Hour, // Таймер не спрацює - підсумок надсилається викликом flush
+	}, unpersisted, func(qe queuedEvent) { got = append(got, qe.event) })
+	if err != nil {
+		t.Fatalf("newSourceLimiter: %v", err)
+	}
+
+	start := time.Now()
+	event := actioner.Event{IP: "10.0.0.1", RuleName: "r", Log: "original", Fields: map[string]string{"k": "v"}}
+	l.aggregate(event, start)
+	l.aggregate(event, start.Add(time.Second))
+	l.aggregate(actioner.Event{IP: "10.0.0.2", RuleName: "r"}, start)
+
+	key := event.IP + "\x00" + event.RuleName
+	l.flush(key)
+	l.flush(key) // Повторний flush нічого не надсилає
+
+	if len(got) != 1 {
+		t.Fatalf("flush dispatched %d events, want 1", len(got))
+	}
+	summary := got[0]
+	tests := []struct{ field, want string }{
+		{"ratelimit.count", "2"},
+		{"ratelimit.first", start.Format(time.RFC3339)},
+		{"ratelimit.last", start.Add(time.Second).Format(time.RFC3339)},
+		{"k", "v"},
+	}
+	for _, tt := range tests {
+		if v := summary.Field(tt.field); v != tt.want {
+			t.Errorf("summary %s = %q, want %q", tt.field, v, tt.want)
+		}
+	}
+	if event.Fields["ratelimit.count"] != "" {
+		t.Errorf("flush modified fields of the original event")
+	}
+	if !summary.Time.Equal(start.Add(time.Second)) {
+		t.Errorf("summary time = %s, want time of the last event", summary.Time)
+	}
+	if _, ok := l.aggregates[event.IP+"\x00r"]; ok {
+		t.Errorf("aggregate kept after flush")
+	}
+	if _, ok := l.aggregates["10.0.0.2\x00r"]; !ok {
+		t.Errorf("flush removed an unrelated aggregate")
+	}
+}
+
+func TestNewSourceLimiter(t *testing.T) {
+	if l, err := newSourceLimiter("test", config.RateLimitConfig{}, nil, nil); l != nil || err != nil {
+		t.Errorf("limiter without limits = %v, %v; want nil", l, err)
+	}
+	if _, err := newSourceLimiter("test", config.RateLimitConfig{Alias: config.RateConfig{Rate: 1}, OnExcess: "delay"}, nil, nil); err == nil {
+		t.Errorf("unknown on_excess accepted")
+	}
+	l, err := newSourceLimiter("test", config.RateLimitConfig{Client: config.RateConfig{Rate: 0.001, Burst: 1}}, nil, nil)
+	if err != nil {
+		t.Fatalf("newSourceLimiter: %v", err)
+	}
+	if ok, _ := l.allowClient("192.0.2.1"); !ok {
+		t.Fatalf("first request rejected")
+	}
+	if ok, retry := l.allowClient("192.0.2.1"); ok || retry <= 0 {
+		t.Errorf("second request: allowClient = %v, %s; want rejection with retry", ok, retry)
+	}
+	if ok, _ := l.allowClient("192.0.2.2"); !ok {
+		t.Errorf("other client shares the bucket")
+	}
+}
+
+func TestRejectedRequestKeepsRateBudget(t *testing.T) {
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer database.Close()
+
+	cfg := &config.Config{Server: config.ServerConfig{
+		Aliases: map[string]string{"/falco": "falco_events"},
+		Sources: map[string]config.SourceConfig{"falco_events": {RateLimit: config.RateLimitConfig{
+			Alias:           config.RateConfig{Rate: 0.001, Burst: 3},
+			OnExcess:        "aggregate",
+			AggregateWindow: 20 * time.Millisecond,
+		}}},
+		Queue: config.QueueConfig{Workers: 1, Size: 2},
+	}}
+	decoders := map[string]decoder.Decoder{"falco_events": decoder.NewFalcoDecoder(nil)}
+	s := NewServer(cfg, database, nil, decoders)
+
+	post := func(events int) (int, batchResult) {
+		body := strings.Repeat(`{"ip":"203.0.113.7","rule":"r"}`+"\n", events)
+		rec := httptest.NewRecorder()
+		s.eventHandler(rec, httptest.NewRequest(http.MethodPost, "/falco", strings.NewReader(body)))
+		var result batchResult
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		return rec.Code, result
+	}
+
+	// 3 події в межах burst не вміщуються в чергу, четверта понад ліміт
+	if code, _ := post(4); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized request: status %d, want 413", code)
+	}
+	time.Sleep(50 * time.Millisecond) // Вікно агрегації минуло
+	if d := s.queue.depth(); d != 0 {
+		t.Fatalf("rejected request dispatched %d aggregated events", d)
+	}
+
+	code, result := post(2)
+	if code != http.StatusAccepted || result.Throttled != 0 {
+		t.Fatalf("request after rejection: status %d, throttled %d; want 202 without throttling", code, result.Throttled)
+	}
+}
+
+func TestRateLimitedEventsInHistory(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     config.RateLimitConfig
+		events    int
+		wantCount int // Подій з IP у журналі після обробки відкладених і підсумкових
+	}{
+		{
+			// Відкладена подія зберігається в durable-черзі ще до обробки
+			name:      "queue",
+			limit:     config.RateLimitConfig{Alias: config.RateConfig{Rate: 10, Burst: 1}, OnExcess: "queue"},
+			events:    2,
+			wantCount: 2,
+		},
+		{
+			// Підсумкова подія рахується як усі агреговані нею події
+			name:      "aggregate",
+			limit:     config.RateLimitConfig{Alias: config.RateConfig{Rate: 0.001, Burst: 1}, OnExcess: "aggregate", AggregateWindow: 100 * time.Millisecond},
+			events:    4,
+			wantCount: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, err := db.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("NewDatabase: %v", err)
+			}
+			defer database.Close()
+
+			cfg := &config.Config{Server: config.ServerConfig{
+				Aliases: map[string]string{"/falco": "falco_events"},
+				Sources: map[string]config.SourceConfig{"falco_events": {RateLimit: tt.limit}},
+				Queue:   config.QueueConfig{Durable: true},
+			}}
+			s := NewServer(cfg, database, nil, map[string]decoder.Decoder{"falco_events": decoder.NewFalcoDecoder(nil)})
+
+			body := strings.Repeat(`{"ip":"203.0.113.7","rule":"r"}`+"\n", tt.events)
+			s.eventHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/falco", strings.NewReader(body)))
+			if tt.limit.OnExcess == "queue" {
+				if pending, _ := database.PendingEvents(); len(pending) != 1 {
+					t.Fatalf("durable queue holds %d delayed events, want 1", len(pending))
+				}
+			}
+
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				n, _ := database.CountEventsByKey("ip", "203.0.113.7", "", time.Hour)
+				if n == tt.wantCount {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("history count = %d, want %d", n, tt.wantCount)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			time.Sleep(20 * time.Millisecond) // Подію знімають з черги після запису в журнал
+			if pending, _ := database.PendingEvents(); len(pending) != 0 {
+				t.Errorf("durable queue holds %d events after processing", len(pending))
+			}
+		})
+	}
+}
@@ -1,111 +1,780 @@
 package server
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cloudedugcp/responseEngine/internal/actioner"
 	"github.com/cloudedugcp/responseEngine/internal/config"
 	"github.com/cloudedugcp/responseEngine/internal/db"
+	"github.com/cloudedugcp/responseEngine/internal/decoder"
+	"github.com/cloudedugcp/responseEngine/internal/metrics"
 	"github.com/cloudedugcp/responseEngine/internal/scenario"
 	"github.com/cloudedugcp/responseEngine/internal/web"
 )
 
+const (
+	defaultHistoryRetention = 7 * 24 * time.Hour
+	defaultMaxBodySize      = 10 << 20
+)
+
 // Server - структура сервера
 type Server struct {
 	cfg       *config.Config
 	db        *db.Database
 	actioners map[string]actioner.Actioner
+	decoders  map[string]decoder.Decoder
+	listeners []actioner.ActionListener
+	auth      map[string]*authenticator
+	dedup     map[string]*deduper // Джерело -> дедуплікатор
+	scenDedup map[string]*deduper // Сценарій -> дедуплікатор
+	limits    map[string]*sourceLimiter
+	matchers  []*scenario.Matcher // За індексом сценарію; nil - сценарій вимкнено
+	whens     []*scenario.When    // За індексом сценарію; nil - без умови when
+	sequences []*scenario.Sequence
+	keys      []string    // За індексом сценарію - ключ кореляції (для послідовності - її ключ)
+	queue     *eventQueue // nil - синхронна обробка
+
+	durable     bool // Події зберігаються в БД до завершення діячів
+	maxAttempts int
 }
 
 // NewServer - створює новий сервер
-func NewServer(cfg *config.Config, database *db.Database, actioners map[string]actioner.Actioner) *Server {
-	return &Server{
+func NewServer(cfg *config.Config, database *db.Database, actioners map[string]actioner.Actioner, decoders map[string]decoder.Decoder) *Server {
+	s := &Server{
 		cfg:       cfg,
 		db:        database,
 		actioners: actioners,
+		decoders:  decoders,
+		auth:      make(map[string]*authenticator),
+		dedup:     make(map[string]*deduper),
+		scenDedup: make(map[string]*deduper),
+		limits:    make(map[string]*sourceLimiter),
+		queue:     newEventQueue(cfg.Server.Queue),
+
+		durable:     cfg.Server.Queue.Durable,
+		maxAttempts: cfg.Server.Queue.MaxAttempts,
+	}
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = defaultMaxAttempts
+	}
+	for name, sc := range cfg.Server.Sources {
+		if a := newAuthenticator(name, sc.Auth); a != nil {
+			s.auth[name] = a
+		}
+		if d := newDeduper(sc.Dedup, defaultDedupFields); d != nil {
+			s.dedup[name] = d
+		}
+		l, err := newSourceLimiter(name, sc.RateLimit, s.persistOne, s.dispatch)
+		if err != nil {
+			log.Printf("Rate limit disabled: %v", err)
+		} else if l != nil {
+			s.limits[name] = l
+		}
+	}
+	s.matchers = make([]*scenario.Matcher, len(cfg.Scenarios))
+	s.whens = make([]*scenario.When, len(cfg.Scenarios))
+	s.sequences = make([]*scenario.Sequence, len(cfg.Scenarios))
+	s.keys = make([]string, len(cfg.Scenarios))
+	for i, sc := range cfg.Scenarios {
+		var m *scenario.Matcher
+		var err error
+		// Один ключ на сценарій: ним групуються стан послідовності, dedup, when, conditions і журнал дій
+		key := sc.Key
+		if sc.Sequence != nil && sc.Sequence.Key != "" {
+			key = sc.Sequence.Key
+		}
+		if key == "" {
+			key = scenario.DefaultKey
+		}
+		if !scenario.ValidKey(key) {
+			log.Printf("Scenario '%s' disabled: unknown correlation key %q", sc.Name, key)
+			continue
+		}
+		s.keys[i] = key
+		if sc.Sequence != nil {
+			seq := *sc.Sequence
+			seq.Key = key
+			if s.sequences[i], err = scenario.NewSequence(sc.Name, seq); err != nil {
+				log.Printf("Scenario '%s' disabled: %v", sc.Name, err)
+			}
+		} else if m, err = scenario.NewMatcher(sc.FalcoRule, sc.Match); err != nil {
+			log.Printf("Scenario '%s' disabled: %v", sc.Name, err)
+		}
+		if sc.When != "" && (m != nil || s.sequences[i] != nil) {
+			if s.whens[i], err = scenario.CompileWhen(sc.When); err != nil {
+				log.Printf("Scenario '%s' disabled: %v", sc.Name, err)
+				m, s.sequences[i] = nil, nil
+			}
+		}
+		s.matchers[i] = m
+		if d := newDeduper(sc.Dedup, defaultScenarioDedupFields); d != nil {
+			s.scenDedup[sc.Name] = d
+		}
+	}
+	for _, a := range actioners {
+		if l, ok := a.(actioner.ActionListener); ok {
+			s.listeners = append(s.listeners, l)
+		}
+		if n, ok := a.(actioner.ActionNotifier); ok {
+			n.SetNotify(s.notifyAction)
+		}
+	}
+	return s
+}
+
+// notifyAction - повідомляє слухачів про виконану дію
+func (s *Server) notifyAction(action, status string, event actioner.Event) {
+	for _, l := range s.listeners {
+		l.OnAction(action, status, event)
 	}
 }
 
 // Start - запускає сервер
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
-
 	mux.HandleFunc("/", s.eventHandler)
-	mux.HandleFunc("/dashboard", web.DashboardHandler(s.db))
+
+	dashboard := mux
+	if s.cfg.Server.Dashboard.Port != "" {
+		dashboard = http.NewServeMux()
+	}
+	dashboard.HandleFunc("/dashboard", web.DashboardHandler(s.db))
+	dashboard.HandleFunc("/metrics", metrics.Handler())
 
 	if s.cfg.Server.ListenPort == "" {
 		log.Println("Warning: ListenPort is empty, defaulting to :8080")
 		s.cfg.Server.ListenPort = ":8080"
 	}
 
-	return http.ListenAndServe(s.cfg.Server.ListenPort, mux)
+	var tlsCfg *tls.Config
+	if s.cfg.Server.TLS.CertFile != "" {
+		var err error
+		if tlsCfg, err = newServerTLSConfig(s.cfg.Server.TLS); err != nil {
+			return err
+		}
+	}
+
+	if s.queue != nil {
+		s.startWorkers()
+	}
+	go s.pruneHistory()
+	if s.durable {
+		if err := s.replayQueue(); err != nil {
+			return err
+		}
+	}
+	if err := s.startSyslog(); err != nil {
+		return err
+	}
+	if err := s.startFalcoGRPC(); err != nil {
+		return err
+	}
+
+	if s.cfg.Server.Dashboard.Port != "" {
+		var dashTLS *tls.Config
+		if s.cfg.Server.Dashboard.TLS {
+			if tlsCfg == nil {
+				return fmt.Errorf("dashboard TLS requires server.tls.cert_file")
+			}
+			dashTLS = tlsCfg.Clone()
+			dashTLS.ClientAuth = tls.NoClientCert
+			dashTLS.GetConfigForClient = nil
+		}
+		ln, err := listen(s.cfg.Server.Dashboard.Port, dashTLS)
+		if err != nil {
+			return fmt.Errorf("failed to listen for dashboard on %s: %v", s.cfg.Server.Dashboard.Port, err)
+		}
+		log.Printf("Serving dashboard on %s", s.cfg.Server.Dashboard.Port)
+		go func() {
+			if err := http.Serve(ln, dashboard); err != nil {
+				log.Printf("Dashboard listener on %s stopped: %v", s.cfg.Server.Dashboard.Port, err)
+			}
+		}()
+	}
+
+	ln, err := listen(s.cfg.Server.ListenPort, tlsCfg)
+	if err != nil {
+		return err
+	}
+	return http.Serve(ln, mux)
+}
+
+// pruneHistory - періодично видаляє з журналу події та стани послідовностей, старші за термін зберігання
+func (s *Server) pruneHistory() {
+	retention := s.cfg.Server.History.Retention
+	if retention <= 0 {
+		retention = defaultHistoryRetention
+	}
+	for ; ; time.Sleep(time.Hour) {
+		n, err := s.db.PruneEvents(time.Now().Add(-retention))
+		if err == nil && n > 0 {
+			log.Printf("Pruned %d events older than %s from history", n, retention)
+		}
+		s.db.PruneSequences(time.Now().Add(-retention))
+		if n, err := s.db.PruneFailedEvents(time.Now().Add(-retention)); err == nil && n > 0 {
+			log.Printf("Pruned %d failed events older than %s from the queue", n, retention)
+		}
+	}
+}
+
+// listen - відкриває TCP-слухач, за потреби загорнутий у TLS
+func listen(addr string, tlsCfg *tls.Config) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		return tls.NewListener(ln, tlsCfg), nil
+	}
+	return ln, nil
 }
 
 // eventHandler - обробляє вхідні події
 func (s *Server) eventHandler(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path
-	if _, ok := s.cfg.Server.Aliases[path]; !ok {
+	source, ok := s.cfg.Server.Aliases[r.URL.Path]
+	if !ok {
 		http.Error(w, "Invalid endpoint", http.StatusNotFound)
 		return
 	}
 
-	var event actioner.Event
-	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	dec, ok := s.decoders[source]
+	if !ok {
+		log.Printf("No decoder configured for source %s", source)
+		http.Error(w, "No decoder configured for endpoint", http.StatusInternalServerError)
+		return
+	}
+
+	if l, ok := s.limits[source]; ok {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		if ok, retry := l.allowClient(client); !ok {
+			log.Printf("Rate limit exceeded for client %s on %s", client, r.URL.Path)
+			w.Header().Set("Retry-After", retryAfterSeconds(retry))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	maxBody := s.cfg.Server.MaxBodySize
+	if maxBody <= 0 {
+		maxBody = defaultMaxBodySize
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Printf("Rejected request to %s from %s: body exceeds %d bytes", r.URL.Path, r.RemoteAddr, maxBody)
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if !s.authorize(w, r, source, body) {
+		return
+	}
+
+	if s.cfg.Server.Sources[source].PubSub.Enabled {
+		s.pubsubHandler(w, r, source, dec, body)
+		return
+	}
+	if isCloudEvent(r) {
+		s.cloudEventsHandler(w, r, source, dec, body)
+		return
+	}
+
+	items := splitBatch(body)
+	if len(items) == 0 {
+		http.Error(w, "Empty request body", http.StatusBadRequest)
 		return
 	}
 
+	pb := s.newPendingBatch()
+	result := s.processItems(source, dec, items, nil, pb)
+	s.enqueue(&result, pb)
+	status := http.StatusOK
+	if result.Accepted == 0 {
+		status = http.StatusBadRequest
+	}
+	s.respond(w, status, result)
+}
+
+// authorize - перевіряє запит налаштуваннями auth джерела; при відмові надсилає 401 і повертає false.
+// Викликається і для джерела, до якого подію перенаправлено (CloudEvents, атрибут Pub/Sub)
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, source string, body []byte) bool {
+	a, ok := s.auth[source]
+	if !ok {
+		return true
+	}
+	if reason, err := a.check(r, body); err != nil {
+		log.Printf("Rejected request to %s from %s for source %s: %v", r.URL.Path, r.RemoteAddr, source, err)
+		metrics.Inc("responseengine_auth_rejected_total", "source", source, "reason", reason)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// pendingBatch - події запиту, що чекають на постановку в чергу. Запит ставиться в чергу
+// цілком (enqueue), навіть якщо його події декодовано кількома викликами processItems
+type pendingBatch struct {
+	events     []actioner.Event
+	admissions []*admission           // Рішення лімітів швидкості; застосовуються, лише якщо чергу прийнято
+	seen       map[batchDedupKey]bool // Повтори в межах запиту, ще не записані в дедуплікатор
+}
+
+type batchDedupKey struct {
+	source string
+	key    dedupKey
+}
+
+// newPendingBatch - створює пакет для асинхронної обробки; nil, якщо черги немає
+func (s *Server) newPendingBatch() *pendingBatch {
+	if s.queue == nil {
+		return nil
+	}
+	return &pendingBatch{seen: make(map[batchDedupKey]bool)}
+}
+
+// processItems - декодує документи пакета та обробляє отримані події; якщо pb не nil - додає
+// їх до pb для постановки в чергу (enqueue). fields додаються до кожної події (наприклад,
+// атрибути повідомлення Pub/Sub)
+func (s *Server) processItems(source string, dec decoder.Decoder, items [][]byte, fields map[string]string, pb *pendingBatch) batchResult {
+	var result batchResult
+	for i, item := range items {
+		res := itemResult{Index: i, Status: "accepted"}
+		events, err := dec.Decode(item)
+		if err != nil {
+			log.Printf("Failed to decode %s event #%d with %s decoder: %v", source, i, dec.Name(), err)
+			res.Status = "rejected"
+			res.Reason = err.Error()
+			result.add(res)
+			continue
+		}
+		for _, event := range events {
+			event.Source = source
+			if len(fields) > 0 {
+				if event.Fields == nil {
+					event.Fields = make(map[string]string, len(fields))
+				}
+				for k, v := range fields {
+					event.Fields[k] = v
+				}
+			}
+			if event.Time.IsZero() {
+				event.Time = time.Now()
+			}
+			if d, ok := s.dedup[source]; ok && pb != nil {
+				k := batchDedupKey{source, d.key(event)}
+				if pb.seen[k] {
+					metrics.Inc("responseengine_dedup_suppressed_total", "source", source)
+					res.Suppressed++
+					continue
+				}
+				pb.seen[k] = true
+			}
+			status, adm := s.admit(source, event)
+			if adm != nil {
+				if pb != nil {
+					pb.admissions = append(pb.admissions, adm)
+				} else {
+					adm.commit()
+				}
+			}
+			switch status {
+			case admitSuppressed:
+				res.Suppressed++
+				continue
+			case admitThrottled:
+				res.Throttled++
+				continue
+			}
+			if pb != nil {
+				pb.events = append(pb.events, event)
+				continue
+			}
+			qe := s.persist(event)[0]
+			qe.sync = true
+			triggered, err := s.runEvent(qe)
+			res.Scenarios = append(res.Scenarios, triggered...)
+			if err != nil {
+				res.Error = err.Error()
+			} else {
+				s.markSeen(source, event)
+			}
+		}
+		res.Events = len(events)
+		result.add(res)
+	}
+
+	if len(items) > 1 {
+		log.Printf("Processed batch from %s: %d accepted, %d rejected, %d failed, %d duplicates suppressed, %d throttled", source, result.Accepted, result.Rejected, result.Failed, result.Suppressed, result.Throttled)
+	}
+	return result
+}
+
+// enqueue - ставить події запиту в чергу одним цілим; якщо для них немає місця, відхиляє всі
+// прийняті елементи, щоб повтор запиту відправником не поставив частину подій у чергу вдруге
+func (s *Server) enqueue(result *batchResult, pb *pendingBatch) {
+	if pb == nil {
+		return
+	}
+	if len(pb.events) == 0 {
+		pb.commit()
+		return
+	}
+	if len(pb.events) > s.queue.size {
+		// Такий пакет не вміститься і в порожню чергу - повтор не допоможе, відправнику слід його розбити
+		log.Printf("Request has %d events, more than queue size %d, rejecting", len(pb.events), s.queue.size)
+		queueRejected(pb.events)
+		pb.cancel()
+		result.rejectAll(fmt.Sprintf("batch has %d events, queue holds at most %d", len(pb.events), s.queue.size))
+		result.tooLarge = true
+		return
+	}
+	queued := s.persist(pb.events...)
+	if !s.queue.tryPush(queued) {
+		s.discard(queued)
+		log.Printf("Event queue is full, rejecting %d events", len(pb.events))
+		queueRejected(pb.events)
+		pb.cancel()
+		result.rejectAll("queue full")
+		result.queueFull = true
+		return
+	}
+	result.Queued = true
+	pb.commit()
+	for _, event := range pb.events {
+		s.markSeen(event.Source, event)
+	}
+}
+
+// commit - застосовує рішення лімітів швидкості прийнятого запиту
+func (pb *pendingBatch) commit() {
+	for _, a := range pb.admissions {
+		a.commit()
+	}
+}
+
+// cancel - повертає токени відхиленого запиту; відкладені й агреговані події не надсилаються
+func (pb *pendingBatch) cancel() {
+	for i := len(pb.admissions) - 1; i >= 0; i-- {
+		pb.admissions[i].cancel()
+	}
+}
+
+// queueRejected - рахує відхилені чергою події за джерелами
+func queueRejected(events []actioner.Event) {
+	counts := make(map[string]int64)
+	for _, event := range events {
+		counts[event.Source]++
+	}
+	for source, n := range counts {
+		metrics.Add("responseengine_queue_rejected_total", n, "source", source)
+	}
+}
+
+const (
+	admitOK = iota
+	admitSuppressed
+	admitThrottled
+)
+
+// admit - перевіряє дедуплікацію та ліміти швидкості джерела для події. Нічого не змінюється,
+// доки запит не прийнято: рішення лімітів застосовується через commit, а подія запам'ятовується
+// як оброблена лише після постановки в чергу чи обробки (markSeen)
+func (s *Server) admit(source string, event actioner.Event) (int, *admission) {
+	if d, ok := s.dedup[source]; ok && d.duplicate(d.key(event), time.Now()) {
+		metrics.Inc("responseengine_dedup_suppressed_total", "source", source)
+		return admitSuppressed, nil
+	}
+	l, ok := s.limits[source]
+	if !ok {
+		return admitOK, nil
+	}
+	a := l.check(event)
+	if !a.allowed() {
+		return admitThrottled, a
+	}
+	return admitOK, a
+}
+
+// markSeen - запам'ятовує прийняті події в дедуплікаторі джерела, щоб відкидати їхні повтори
+func (s *Server) markSeen(source string, events ...actioner.Event) {
+	d, ok := s.dedup[source]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	for _, event := range events {
+		d.record(d.key(event), now)
+	}
+}
+
+// dispatch - передає збережену подію на обробку поза запитом: у чергу або одразу
+func (s *Server) dispatch(qe queuedEvent) {
+	event := qe.event
+	if s.queue != nil {
+		s.queue.push(qe) // Чекаємо, доки в черзі звільниться місце
+		s.markSeen(event.Source, event)
+		return
+	}
+	if _, err := s.runEvent(qe); err != nil {
+		log.Printf("Failed to process %s event (Rule=%s, IP=%s): %v", event.Source, event.RuleName, event.IP, err)
+		return
+	}
+	s.markSeen(event.Source, event)
+}
+
+// respond - надсилає підсумок обробки; при асинхронній обробці успіх означає 202,
+// переповнена черга - 429/503 з Retry-After, а пакет, більший за чергу, - 413
+func (s *Server) respond(w http.ResponseWriter, status int, result batchResult) {
+	switch {
+	case result.tooLarge:
+		status = http.StatusRequestEntityTooLarge
+	case result.queueFull:
+		w.Header().Set("Retry-After", retryAfterSeconds(s.queue.retryAfter))
+		status = s.queue.fullStatus
+	case result.Queued && status == http.StatusOK:
+		status = http.StatusAccepted
+	}
+	writeResult(w, status, result)
+}
+
+// retryAfterSeconds - значення Retry-After у цілих секундах, округлене вгору і не менше 1
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(max(1, int(math.Ceil(d.Seconds()))))
+}
+
+// writeResult - надсилає підсумок обробки у форматі JSON
+func writeResult(w http.ResponseWriter, status int, result batchResult) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Failed to write batch result: %v", err)
+	}
+}
+
+// processEvent - записує подію в журнал і оцінює сценарії; повертає імена спрацьованих сценаріїв
+// і їхніх діячів. Діячі виконуються окремо (runActions), щоб durable-черга могла зберегти їх
+// до виконання і після перезапуску не оцінювати подію вдруге
+func (s *Server) processEvent(qe queuedEvent) ([]string, []pendingAction) {
+	event := qe.event
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
 	if event.Log != "" {
-		log.Printf("Received event: IP=%s, Rule=%s, Log=%s, Time=%s", event.IP, event.RuleName, event.Log, time.Now().Format(time.RFC3339))
+		log.Printf("Received event: Source=%s, IP=%s, Rule=%s, Priority=%s, Log=%s, Time=%s", event.Source, event.IP, event.RuleName, event.Priority, event.Log, event.Time.Format(time.RFC3339))
 	} else {
-		log.Printf("Received event: IP=%s, Rule=%s, Time=%s", event.IP, event.RuleName, time.Now().Format(time.RFC3339))
+		log.Printf("Received event: Source=%s, IP=%s, Rule=%s, Priority=%s, Time=%s", event.Source, event.IP, event.RuleName, event.Priority, event.Time.Format(time.RFC3339))
+	}
+
+	recorded := true
+	if payload, err := json.Marshal(event); err == nil {
+		sum := sha256.Sum256(payload)
+		recorded, _ = s.db.RecordEvent(db.EventRecord{
+			IP:          event.IP,
+			Rule:        event.RuleName,
+			Source:      event.Source,
+			Timestamp:   event.Time,
+			PayloadHash: hex.EncodeToString(sum[:]),
+			Keys:        scenario.CorrelationKeys(event),
+			QueueID:     qe.id,
+			Count:       s.limits[event.Source].aggregatedCount(event),
+		})
 	}
 
-	if event.IP != "" {
+	// Подію з durable-черги вже оцінювали до перезапуску, але діячів не встигли зберегти:
+	// стан послідовностей і дедуплікатори сценаріїв вона вже змінила, тож вдруге не змінює
+	replayed := !recorded && qe.id != 0
+	switch {
+	case replayed:
+		log.Printf("Queued event %d is already in history, not counting it again", qe.id)
+	case event.IP != "":
 		if err := s.db.LogAction(event.IP, event.RuleName, "received", time.Now()); err != nil {
 			log.Printf("Failed to log event to database: %v", err)
 		}
-	} else {
+	default:
 		log.Printf("Warning: Event with empty IP received (Rule=%s)", event.RuleName)
 	}
 
-	for _, sc := range s.cfg.Scenarios {
-		if sc.FalcoRule == event.RuleName && event.IP != "" {
+	var triggered []string
+	var actions []pendingAction
+	for i, sc := range s.cfg.Scenarios {
+		if sc.Source != "" && sc.Source != event.Source {
+			continue
+		}
+		matched := false
+		if sq := s.sequences[i]; sq != nil {
+			if replayed {
+				continue // Крок уже зараховано; якщо він завершив послідовність, спрацювання втрачено
+			}
+			matched = sq.Advance(event, s.db) // Стан просувається і подіями без значення ключа сценарію
+		} else if m := s.matchers[i]; m != nil {
+			matched = m.Match(event)
+		}
+		key := s.keys[i]
+		value := scenario.CorrelationKey(key, event)
+		if matched && value != "" {
+			if d, ok := s.scenDedup[sc.Name]; ok && d.duplicate(d.key(event, key, value), time.Now()) {
+				log.Printf("Scenario '%s' skipped duplicate event for %s=%s", sc.Name, key, value)
+				metrics.Inc("responseengine_dedup_suppressed_total", "scenario", sc.Name)
+				continue
+			}
+			if w := s.whens[i]; w != nil {
+				ok, err := w.Eval(event, key, s.db)
+				if err != nil {
+					log.Printf("Scenario '%s': %v", sc.Name, err)
+				}
+				if !ok {
+					log.Printf("Scenario '%s' when condition not met for %s=%s", sc.Name, key, value)
+					continue
+				}
+			}
+
 			shouldExecute := true
 			if sc.Conditions != nil {
-				shouldExecute = scenario.ShouldTrigger(*sc.Conditions, event, s.db)
+				shouldExecute = scenario.ShouldTrigger(*sc.Conditions, key, event, s.db)
 				if shouldExecute {
-					log.Printf("Scenario '%s' triggered for IP=%s (conditions met)", sc.Name, event.IP)
+					log.Printf("Scenario '%s' triggered for %s=%s (conditions met)", sc.Name, key, value)
 				} else {
-					log.Printf("Scenario '%s' conditions not met for IP=%s", sc.Name, event.IP)
+					log.Printf("Scenario '%s' conditions not met for %s=%s", sc.Name, key, value)
 				}
 			}
 
 			if shouldExecute {
+				triggered = append(triggered, sc.Name)
+				target := forScenario(event, sc.Name, key, value)
+				planned := 0
 				for _, sa := range sc.Actioners {
-					if actioner, ok := s.actioners[sa.Name]; ok {
-						err := actioner.Execute(event, sa.Params)
-						if err != nil {
-							log.Printf("Error executing actioner %s: %v", sa.Name, err)
-						} else {
-							log.Printf("Actioner '%s' executed successfully for IP=%s", sa.Name, event.IP)
-							actionType := "store"
-							status := "stored"
-							if sa.Name == "firewall" {
-								actionType = "block"
-								status = "blocked"
-							}
-							if err := s.db.LogAction(event.IP, actionType, status, time.Now()); err != nil {
-								log.Printf("Failed to log action %s to database: %v", actionType, err)
-							}
-						}
+					if _, ok := s.actioners[sa.Name]; ok {
+						actions = append(actions, pendingAction{Event: target, Action: sa})
+						planned++
 					}
 				}
+				if planned == 0 && !replayed {
+					s.recordScenarios([]pendingAction{{Event: target}}, nil)
+				}
 			}
 		}
 	}
-	w.WriteHeader(http.StatusOK)
+	return triggered, actions
+}
+
+// pendingAction - діяч сценарію, що ще не виконався успішно; зберігається в durable-черзі
+// і при збої повторюється окремо від решти сценарію
+type pendingAction struct {
+	Event  actioner.Event          `json:"event"` // Подія з полями сценарію (forScenario)
+	Action config.ScenarioActioner `json:"action"`
+}
+
+// runActions - виконує діячів по черзі; повертає тих, що завершилися помилкою. Для події з
+// durable-черги після кожного діяча зберігає тих, що лишилися, щоб після перезапуску не
+// повторювати вже виконані
+func (s *Server) runActions(id int64, actions []pendingAction) []pendingAction {
+	var failed []pendingAction
+	for i, pa := range actions {
+		if !s.runAction(pa) {
+			failed = append(failed, pa)
+		}
+		if id != 0 && i < len(actions)-1 {
+			remaining := append(append([]pendingAction(nil), failed...), actions[i+1:]...)
+			s.saveActions(id, remaining)
+		}
+	}
+	s.recordScenarios(actions, failed)
+	return failed
+}
+
+// recordScenarios - запам'ятовує в дедуплікаторах сценарії, всі діячі яких виконалися;
+// повтори пригнічуються лише після успішної дії, інакше повторна доставка мала б шанс її виконати
+func (s *Server) recordScenarios(actions, failed []pendingAction) {
+	skip := make(map[string]bool, len(failed))
+	for _, pa := range failed {
+		skip[pa.Event.Field(actioner.FieldScenario)] = true
+	}
+	for _, pa := range actions {
+		name := pa.Event.Field(actioner.FieldScenario)
+		d, ok := s.scenDedup[name]
+		if !ok || skip[name] {
+			continue
+		}
+		skip[name] = true
+		d.record(d.key(pa.Event, pa.Event.Field(actioner.FieldCorrelationKey), pa.Event.Field(actioner.FieldCorrelationValue)), time.Now())
+	}
+}
+
+// runAction - виконує діяча сценарію; false, якщо він завершився помилкою
+func (s *Server) runAction(pa pendingAction) bool {
+	target, sa := pa.Event, pa.Action
+	key := target.Field(actioner.FieldCorrelationKey)
+	value := target.Field(actioner.FieldCorrelationValue)
+	act, ok := s.actioners[sa.Name]
+	if !ok {
+		return true
+	}
+	err := act.Execute(target, sa.Params)
+	if errors.Is(err, actioner.ErrNoAction) {
+		log.Printf("Actioner '%s' took no action for %s=%s", sa.Name, key, value)
+		return true
+	}
+	if err != nil {
+		log.Printf("Error executing actioner %s: %v", sa.Name, err)
+		return false
+	}
+	log.Printf("Actioner '%s' executed successfully for %s=%s", sa.Name, key, value)
+	if _, ok := act.(actioner.ActionListener); ok {
+		return true // Діяч-слухач лише повідомляє про дії, сам дією не є
+	}
+	actionType := "store"
+	status := "stored"
+	if act.Name() == "firewall" {
+		actionType = "block"
+		status = "blocked"
+	}
+	if err := s.db.LogAction(target.Subject(), actionType, status, time.Now()); err != nil {
+		log.Printf("Failed to log action %s to database: %v", actionType, err)
+	}
+	s.notifyAction(actionType, status, target)
+	return true
+}
+
+// actionsError - помилка обробки події зі списком діячів, що збоїли
+func actionsError(failed []pendingAction) error {
+	names := make([]string, len(failed))
+	for i, f := range failed {
+		names[i] = f.Action.Name
+	}
+	return fmt.Errorf("actioners failed: %s", strings.Join(names, ", "))
+}
+
+// forScenario - копія події з іменем сценарію та його ключем кореляції в полях, щоб діячі знали суб'єкт дії
+func forScenario(event actioner.Event, name, key, value string) actioner.Event {
+	fields := make(map[string]string, len(event.Fields)+4)
+	for k, v := range event.Fields {
+		fields[k] = v
+	}
+	fields[actioner.FieldScenario] = name
+	fields[actioner.FieldCorrelationKey] = key
+	fields[actioner.FieldCorrelationValue] = value
+	fields[actioner.FieldCorrelationSubject] = db.Subject(key, value)
+	event.Fields = fields
+	return event
 }
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/config"
+	"github.com/cloudedugcp/responseEngine/internal/metrics"
+)
+
+// maxSyslogMessage - максимальний розмір одного повідомлення syslog
+const maxSyslogMessage = 64 * 1024
+
+const (
+	defaultSyslogIdleTimeout    = 5 * time.Minute
+	defaultSyslogMaxConnections = 100
+)
+
+// startSyslog - запускає слухачів syslog з конфігурації
+func (s *Server) startSyslog() error {
+	for _, sc := range s.cfg.Server.Syslog {
+		if _, ok := s.decoders[sc.Source]; !ok {
+			return fmt.Errorf("syslog listener %s %s: no decoder configured for source %q", sc.Protocol, sc.Address, sc.Source)
+		}
+		peers, err := parsePeers(sc.AllowedPeers)
+		if err != nil {
+			return fmt.Errorf("syslog listener %s %s: %v", sc.Protocol, sc.Address, err)
+		}
+		switch sc.Protocol {
+		case "udp", "":
+			conn, err := net.ListenPacket("udp", sc.Address)
+			if err != nil {
+				return fmt.Errorf("failed to listen for syslog on udp %s: %v", sc.Address, err)
+			}
+			go s.serveSyslogUDP(conn, sc, peers)
+		case "tcp":
+			ln, err := net.Listen("tcp", sc.Address)
+			if err != nil {
+				return fmt.Errorf("failed to listen for syslog on tcp %s: %v", sc.Address, err)
+			}
+			go s.serveSyslogStream(ln, sc, peers)
+		case "tls":
+			cert, err := tls.LoadX509KeyPair(sc.CertFile, sc.KeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to load syslog TLS certificate: %v", err)
+			}
+			ln, err := tls.Listen("tcp", sc.Address, &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				MinVersion:   tls.VersionTLS12,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to listen for syslog on tls %s: %v", sc.Address, err)
+			}
+			go s.serveSyslogStream(ln, sc, peers)
+		default:
+			return fmt.Errorf("unknown syslog protocol %q (expected udp, tcp or tls)", sc.Protocol)
+		}
+		log.Printf("Listening for syslog (%s) on %s, source %s", sc.Protocol, sc.Address, sc.Source)
+	}
+	return nil
+}
+
+// serveSyslogUDP - приймає повідомлення syslog по UDP (одна датаграма - одне повідомлення)
+func (s *Server) serveSyslogUDP(conn net.PacketConn, sc config.SyslogConfig, peers []*net.IPNet) {
+	buf := make([]byte, maxSyslogMessage)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			log.Printf("Syslog UDP listener on %s stopped: %v", sc.Address, err)
+			return
+		}
+		if !peerAllowed(peers, addr) {
+			metrics.Inc("responseengine_syslog_denied_total", "source", sc.Source)
+			continue // Без журналу: підроблені датаграми не повинні засмічувати лог
+		}
+		msg := append([]byte(nil), bytes.TrimSpace(buf[:n])...)
+		s.handleSyslog(sc.Source, msg, addr)
+	}
+}
+
+// serveSyslogStream - приймає з'єднання syslog по TCP/TLS; з'єднання понад max_connections
+// відхиляються, щоб відправники, що не закривають з'єднань, не вичерпали ресурси
+func (s *Server) serveSyslogStream(ln net.Listener, sc config.SyslogConfig, peers []*net.IPNet) {
+	if sc.IdleTimeout <= 0 {
+		sc.IdleTimeout = defaultSyslogIdleTimeout
+	}
+	if sc.MaxConnections <= 0 {
+		sc.MaxConnections = defaultSyslogMaxConnections
+	}
+	slots := make(chan struct{}, sc.MaxConnections)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Printf("Syslog %s listener on %s stopped: %v", sc.Protocol, sc.Address, err)
+			return
+		}
+		if !peerAllowed(peers, conn.RemoteAddr()) {
+			log.Printf("Rejected syslog connection from %s: peer not in allowed_peers", conn.RemoteAddr())
+			metrics.Inc("responseengine_syslog_denied_total", "source", sc.Source)
+			conn.Close()
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			log.Printf("Rejected syslog connection from %s: %d connections already open", conn.RemoteAddr(), sc.MaxConnections)
+			metrics.Inc("responseengine_syslog_rejected_total", "source", sc.Source)
+			conn.Close()
+			continue
+		}
+		go func() {
+			defer func() { <-slots }()
+			s.serveSyslogConn(conn, sc)
+		}()
+	}
+}
+
+// serveSyslogConn - читає повідомлення з потоку TCP/TLS до закриття з'єднання; з'єднання,
+// що не надсилає повного повідомлення довше за idle_timeout, закривається
+func (s *Server) serveSyslogConn(conn net.Conn, sc config.SyslogConfig) {
+	defer conn.Close()
+	r := bufio.NewReaderSize(conn, maxSyslogMessage)
+	for {
+		conn.SetReadDeadline(time.Now().Add(sc.IdleTimeout))
+		msg, err := readSyslogFrame(r)
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("Syslog connection from %s closed: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		msg = bytes.TrimSpace(msg)
+		if len(msg) > 0 {
+			s.handleSyslog(sc.Source, msg, conn.RemoteAddr())
+		}
+	}
+}
+
+// readSyslogFrame - читає одне повідомлення: з підрахунком октетів (RFC 6587, "LEN MSG")
+// або до нового рядка. r має буфер розміром maxSyslogMessage, тож довший рядок - помилка, як і
+// завелика довжина кадру; після помилки потік не синхронізовано, і з'єднання слід закрити
+func readSyslogFrame(r *bufio.Reader) ([]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		size := 0
+		for {
+			c, err := r.ReadByte()
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return nil, err
+			}
+			if c == ' ' {
+				break
+			}
+			if c < '0' || c > '9' {
+				return nil, fmt.Errorf("invalid syslog frame length")
+			}
+			size = size*10 + int(c-'0')
+			if size > maxSyslogMessage {
+				return nil, fmt.Errorf("syslog frame exceeds %d bytes", maxSyslogMessage)
+			}
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return msg, nil
+	}
+
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, fmt.Errorf("syslog message exceeds %d bytes", maxSyslogMessage)
+	}
+	if len(line) == 0 && err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), line...), nil // ReadSlice повертає зріз буфера, що перезапишеться
+}
+
+// parsePeers - розбирає allowed_peers: CIDR або окремі адреси
+func parsePeers(peers []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, p := range peers {
+		p = strings.TrimSpace(p)
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid allowed peer %q", p)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed peer %q: %v", p, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// peerAllowed - true, якщо список порожній або адреса відправника входить до однієї з мереж
+func peerAllowed(nets []*net.IPNet, addr net.Addr) bool {
+	if len(nets) == 0 {
+		return true
+	}
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.TCPAddr:
+		ip = a.IP
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return false
+		}
+		ip = net.ParseIP(host)
+	}
+	for _, n := range nets {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSyslog - обробляє одне повідомлення syslog через декодер джерела
+func (s *Server) handleSyslog(source string, msg []byte, peer net.Addr) {
+	pb := s.newPendingBatch()
+	result := s.processItems(source, s.decoders[source], [][]byte{msg}, map[string]string{"syslog.peer": peer.String()}, pb)
+	s.enqueue(&result, pb)
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/config"
+)
+
+func TestReadSyslogFrame(t *testing.T) {
+	long := strings.Repeat("a", maxSyslogMessage)
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool // Після останнього кадру - помилка, а не io.EOF
+	}{
+		{name: "empty", input: ""},
+		{name: "newline", input: "<13>one\n<13>two\n", want: []string{"<13>one\n", "<13>two\n"}},
+		{name: "newline without trailing", input: "<13>one\n<13>two", want: []string{"<13>one\n", "<13>two"}},
+		{name: "octet counting", input: "7 <13>one7 <13>two", want: []string{"<13>one", "<13>two"}},
+		{name: "octet counting with newline inside", input: "7 <13>a\nb\n", want: []string{"<13>a\nb", "\n"}},
+		{name: "mixed", input: "7 <13>one<13>two\n", want: []string{"<13>one", "<13>two\n"}},
+		{name: "truncated frame", input: "10 <13>one", wantErr: true},
+		{name: "truncated length", input: "12", wantErr: true},
+		{name: "invalid length", input: "12x <13>one", wantErr: true},
+		{name: "oversized length", input: "99999999999999999999 x", wantErr: true},
+		{name: "length just over limit", input: "65537 x", wantErr: true},
+		{name: "oversized line", input: "<" + long + "\n", wantErr: true},
+		{name: "line at limit", input: long[:maxSyslogMessage-1] + "\n", want: []string{long[:maxSyslogMessage-1] + "\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReaderSize(strings.NewReader(tt.input), maxSyslogMessage)
+			var got []string
+			var err error
+			for {
+				var msg []byte
+				msg, err = readSyslogFrame(r)
+				if err != nil {
+					break
+				}
+				got = append(got, string(msg))
+			}
+			if tt.wantErr && err == io.EOF {
+				t.Fatalf("expected framing error, got clean EOF")
+			}
+			if !tt.wantErr && err != io.EOF {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d frames %q, want %d %q", len(got), got, len(tt.want), tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("frame %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPeerAllowed(t *testing.T) {
+	nets, err := parsePeers([]string{"10.0.0.0/8", "192.168.1.10", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("parsePeers: %v", err)
+	}
+	tests := []struct {
+		addr net.Addr
+		want bool
+	}{
+		{&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 514}, true},
+		{&net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 40000}, true},
+		{&net.TCPAddr{IP: net.ParseIP("192.168.1.11"), Port: 40000}, false},
+		{&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 514}, true},
+		{&net.UDPAddr{IP: net.ParseIP("8.8.8.8"), Port: 514}, false},
+	}
+	for _, tt := range tests {
+		if got := peerAllowed(nets, tt.addr); got != tt.want {
+			t.Errorf("peerAllowed(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+	if !peerAllowed(nil, &net.UDPAddr{IP: net.ParseIP("8.8.8.8")}) {
+		t.Errorf("empty allowlist must accept every peer")
+	}
+	for _, bad := range []string{"10.0.0.0/33", "not-an-ip", ""} {
+		if _, err := parsePeers([]string{bad}); err == nil {
+			t.Errorf("parsePeers(%q) accepted invalid peer", bad)
+		}
+	}
+}
+
+func TestSyslogStreamLimits(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+	s := &Server{}
+	go s.serveSyslogStream(ln, config.SyslogConfig{Protocol: "tcp", Source: "test", IdleTimeout: 200 * time.Millisecond, MaxConnections: 1}, nil)
+
+	// closedWithin - true, якщо сервер закрив з'єднання не пізніше d
+	closedWithin := func(conn net.Conn, d time.Duration) bool {
+		conn.SetReadDeadline(time.Now().Add(d))
+		_, err := conn.Read(make([]byte, 1))
+		return err == io.EOF
+	}
+
+	idle, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer idle.Close()
+	time.Sleep(50 * time.Millisecond) // Сервер прийняв перше з'єднання
+	extra, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer extra.Close()
+	if !closedWithin(extra, 100*time.Millisecond) {
+		t.Errorf("connection over max_connections was not rejected")
+	}
+	if !closedWithin(idle, time.Second) {
+		t.Errorf("idle connection was not closed after idle_timeout")
+	}
+
+	// Закрите з'єднання звільнило місце (після закриття, тож трохи чекаємо)
+	time.Sleep(50 * time.Millisecond)
+	next, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer next.Close()
+	if closedWithin(next, 100*time.Millisecond) {
+		t.Errorf("connection rejected after a slot was freed")
+	}
+}
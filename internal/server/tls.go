@@ -0,0 +1,150 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cloudedugcp/responseEngine/internal/config"
+)
+
+const defaultTLSReloadInterval = 30 * time.Second
+
+// certReloader - тримає актуальні сертифікат сервера та CA клієнтів, перечитуючи файли при зміні
+type certReloader struct {
+	cfg config.TLSConfig
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	clients *x509.CertPool
+	mtimes  map[string]time.Time
+}
+
+// newServerTLSConfig - будує tls.Config для HTTP-сервера з автоматичним перезавантаженням сертифікатів
+func newServerTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	minVersion := uint16(tls.VersionTLS12)
+	switch cfg.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		minVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported tls min_version %q (expected 1.2 or 1.3)", cfg.MinVersion)
+	}
+
+	clientAuth := tls.NoClientCert
+	switch cfg.ClientAuth {
+	case "":
+		if cfg.ClientCAFile != "" {
+			clientAuth = tls.RequireAndVerifyClientCert
+		}
+	case "require":
+		clientAuth = tls.RequireAndVerifyClientCert
+	case "verify_if_given":
+		clientAuth = tls.VerifyClientCertIfGiven
+	case "request":
+		clientAuth = tls.RequestClientCert
+	default:
+		return nil, fmt.Errorf("unknown tls client_auth %q (expected require, verify_if_given or request)", cfg.ClientAuth)
+	}
+	if cfg.ClientCAFile == "" && (clientAuth == tls.RequireAndVerifyClientCert || clientAuth == tls.VerifyClientCertIfGiven) {
+		return nil, fmt.Errorf("tls client_auth %q requires client_ca_file", cfg.ClientAuth)
+	}
+
+	r := &certReloader{cfg: cfg, mtimes: make(map[string]time.Time)}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	interval := cfg.ReloadInterval
+	if interval <= 0 {
+		interval = defaultTLSReloadInterval
+	}
+	go r.watch(interval)
+
+	base := &tls.Config{
+		MinVersion:     minVersion,
+		ClientAuth:     clientAuth,
+		GetCertificate: r.getCertificate,
+	}
+	tlsCfg := base.Clone()
+	// Пул CA клієнтів може змінитися, тому конфігурація будується на кожне рукостискання
+	tlsCfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := base.Clone()
+		r.mu.RLock()
+		c.ClientCAs = r.clients
+		r.mu.RUnlock()
+		return c, nil
+	}
+	return tlsCfg, nil
+}
+
+// load - читає сертифікат, ключ і CA клієнтів з диска
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	var clients *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %v", err)
+		}
+		clients = x509.NewCertPool()
+		if !clients.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA file %s", r.cfg.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clients = clients
+	r.mu.Unlock()
+	r.changed()
+	return nil
+}
+
+// changed - оновлює час модифікації файлів; true, якщо хоча б один файл змінився
+func (r *certReloader) changed() bool {
+	changed := false
+	for _, name := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.ClientCAFile} {
+		if name == "" {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(r.mtimes[name]) {
+			r.mtimes[name] = info.ModTime()
+			changed = true
+		}
+	}
+	return changed
+}
+
+// watch - періодично перевіряє файли і перезавантажує сертифікати при зміні
+func (r *certReloader) watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		if !r.changed() {
+			continue
+		}
+		// Під час ротації файли можуть бути записані не одночасно - лишаємо старий сертифікат до наступної спроби
+		if err := r.load(); err != nil {
+			log.Printf("TLS certificate reload failed, keeping previous certificate: %v", err)
+			r.mtimes = make(map[string]time.Time)
+			continue
+		}
+		log.Printf("Reloaded TLS certificate from %s", r.cfg.CertFile)
+	}
+}
+
+// getCertificate - повертає поточний сертифікат сервера
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}